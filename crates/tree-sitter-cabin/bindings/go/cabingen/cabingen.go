@@ -0,0 +1,367 @@
+// Package cabingen generates Cabin declarations from Go packages, so that Go
+// domain types can be used from Cabin scripts without transcribing them.
+//
+// Exported structs become groups, exported interfaces become groups of
+// actions, and named string or integer types with constants become eithers.
+// Methods of structs are emitted as extend stubs, one per interface of the
+// package that the struct implements plus one for the remaining methods.
+// Names that are Cabin keywords get a trailing underscore, and names that
+// can't be written in Cabin at all are an error.
+//
+// The grammar doesn't accept parameter lists in action types yet, so methods
+// become actions with only a return type, preceded by a comment listing their
+// parameters. The output is written in a single fixed layout, since there is
+// no Cabin formatter to run it through.
+package cabingen
+
+import (
+	"bytes"
+	"fmt"
+	"go/types"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Load type-checks the Go packages matching patterns, resolved relative to
+// dir, for use with Generate. Dependencies are type-checked from source too,
+// so that Load doesn't depend on the export data format of the toolchain.
+func Load(dir string, patterns ...string) ([]*types.Package, error) {
+	config := &packages.Config{
+		Dir:  dir,
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedSyntax | packages.NeedImports | packages.NeedDeps,
+	}
+	loaded, err := packages.Load(config, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	var result []*types.Package
+	for _, pkg := range loaded {
+		if len(pkg.Errors) > 0 {
+			return nil, fmt.Errorf("loading %s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		result = append(result, pkg.Types)
+	}
+	return result, nil
+}
+
+// Generate returns Cabin declarations for the exported types of pkg.
+func Generate(pkg *types.Package) ([]byte, error) {
+	g := &generator{pkg: pkg, emitted: map[*types.TypeName]bool{}}
+	scope := pkg.Scope()
+
+	var names []*types.TypeName
+	enums := map[*types.TypeName][]*types.Const{}
+	for _, name := range scope.Names() {
+		switch object := scope.Lookup(name).(type) {
+		case *types.TypeName:
+			if object.Exported() && !object.IsAlias() {
+				names = append(names, object)
+			}
+		case *types.Const:
+			if named, ok := object.Type().(*types.Named); ok && object.Exported() && named.Obj().Pkg() == pkg {
+				enums[named.Obj()] = append(enums[named.Obj()], object)
+			}
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Pos() < names[j].Pos() })
+
+	var declarations []func()
+	var interfaces []*types.TypeName
+	for _, name := range names {
+		switch underlying := name.Type().Underlying().(type) {
+		case *types.Struct:
+			g.emitted[name] = true
+			declarations = append(declarations, func() { g.group(name, underlying) })
+		case *types.Interface:
+			g.emitted[name] = true
+			interfaces = append(interfaces, name)
+			declarations = append(declarations, func() { g.iface(name, underlying) })
+		case *types.Basic:
+			if constants := enums[name]; len(constants) > 0 && underlying.Info()&(types.IsString|types.IsInteger) != 0 {
+				g.emitted[name] = true
+				declarations = append(declarations, func() { g.either(name, constants) })
+			}
+		}
+	}
+	for _, declaration := range declarations {
+		declaration()
+	}
+	for _, name := range names {
+		if _, ok := name.Type().Underlying().(*types.Struct); ok {
+			g.extend(name, interfaces)
+		}
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+	return g.out.Bytes(), nil
+}
+
+type generator struct {
+	pkg     *types.Package
+	emitted map[*types.TypeName]bool
+	out     bytes.Buffer
+	// err is the first name that can't be written in Cabin.
+	err error
+}
+
+// keywords are the words of the Cabin grammar, which can't be names.
+var keywords = map[string]bool{
+	"action": true, "and": true, "as": true, "editable": true, "either": true,
+	"extend": true, "foreach": true, "group": true, "if": true, "in": true,
+	"is": true, "let": true, "match": true, "new": true, "or": true,
+	"otherwise": true, "run": true, "visible": true, "while": true,
+}
+
+// identifier matches the identifiers of the Cabin grammar.
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// name returns the Cabin name of a Go identifier: the identifier itself for
+// a type, or its snake_case for anything else. A name that is a keyword gets
+// a trailing underscore.
+func (g *generator) name(goName string, typeName bool) string {
+	name := goName
+	if !typeName {
+		name = SnakeCase(goName)
+	}
+	if keywords[name] {
+		return name + "_"
+	}
+	if !identifier.MatchString(name) && g.err == nil {
+		g.err = fmt.Errorf("%s: %s isn't a valid Cabin name", g.pkg.Path(), goName)
+	}
+	return name
+}
+
+func (g *generator) typeParameters(name *types.TypeName) string {
+	parameters := name.Type().(*types.Named).TypeParams()
+	if parameters.Len() == 0 {
+		return ""
+	}
+	var list []string
+	for i := 0; i < parameters.Len(); i++ {
+		list = append(list, g.name(parameters.At(i).Obj().Name(), true)+": Any")
+	}
+	return "<" + strings.Join(list, ", ") + ">"
+}
+
+func (g *generator) group(name *types.TypeName, structure *types.Struct) {
+	fmt.Fprintf(&g.out, "let visible %s = group%s {\n", g.name(name.Name(), true), g.typeParameters(name))
+	for i := 0; i < structure.NumFields(); i++ {
+		field := structure.Field(i)
+		if !field.Exported() {
+			continue
+		}
+		fieldName := g.name(field.Name(), false)
+		if tag, ok := reflect.StructTag(structure.Tag(i)).Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			// The field has to keep the name of the JSON key, so one that
+			// isn't a Cabin name can't be renamed.
+			if tagName != "" && (keywords[tagName] || !identifier.MatchString(tagName)) && g.err == nil {
+				g.err = fmt.Errorf("%s.%s: the JSON name %q of field %s isn't a valid Cabin name", g.pkg.Path(), name.Name(), tagName, field.Name())
+			}
+			if tagName != "" {
+				fieldName = tagName
+			}
+		}
+		fmt.Fprintf(&g.out, "\tvisible %s: %s,\n", fieldName, g.cabinType(field.Type()))
+	}
+	g.out.WriteString("};\n\n")
+}
+
+func (g *generator) iface(name *types.TypeName, iface *types.Interface) {
+	fmt.Fprintf(&g.out, "let visible %s = group%s {\n", g.name(name.Name(), true), g.typeParameters(name))
+	for i := 0; i < iface.NumMethods(); i++ {
+		method := iface.Method(i)
+		if method.Exported() {
+			action, parameters := g.action(method.Type().(*types.Signature))
+			g.parameters(parameters)
+			fmt.Fprintf(&g.out, "\tvisible %s: %s,\n", g.name(method.Name(), false), action)
+		}
+	}
+	g.out.WriteString("};\n\n")
+}
+
+func (g *generator) either(name *types.TypeName, constants []*types.Const) {
+	sort.Slice(constants, func(i, j int) bool { return constants[i].Pos() < constants[j].Pos() })
+	fmt.Fprintf(&g.out, "let visible %s = either {\n", g.name(name.Name(), true))
+	for _, constant := range constants {
+		variant := strings.TrimPrefix(constant.Name(), name.Name())
+		if variant == "" {
+			variant = constant.Name()
+		}
+		fmt.Fprintf(&g.out, "\t%s,\n", g.name(variant, false))
+	}
+	g.out.WriteString("};\n\n")
+}
+
+// extend emits the extend stubs for the exported methods of a struct.
+func (g *generator) extend(name *types.TypeName, interfaces []*types.TypeName) {
+	methods := types.NewMethodSet(types.NewPointer(name.Type()))
+	covered := map[string]bool{}
+	for _, iface := range interfaces {
+		underlying := iface.Type().Underlying().(*types.Interface)
+		if underlying.NumMethods() == 0 || !types.Implements(types.NewPointer(name.Type()), underlying) {
+			continue
+		}
+		fmt.Fprintf(&g.out, "let visible %sAs%s = extend %s as %s {\n", name.Name(), iface.Name(), name.Name(), iface.Name())
+		for i := 0; i < underlying.NumMethods(); i++ {
+			method := underlying.Method(i)
+			covered[method.Name()] = true
+			g.stub(methods, method.Name())
+		}
+		g.out.WriteString("};\n\n")
+	}
+
+	var remaining []string
+	for i := 0; i < methods.Len(); i++ {
+		method := methods.At(i).Obj()
+		if method.Exported() && !covered[method.Name()] {
+			remaining = append(remaining, method.Name())
+		}
+	}
+	if len(remaining) == 0 {
+		return
+	}
+	fmt.Fprintf(&g.out, "let visible %sMethods = extend %s {\n", name.Name(), name.Name())
+	for _, method := range remaining {
+		g.stub(methods, method)
+	}
+	g.out.WriteString("};\n\n")
+}
+
+func (g *generator) stub(methods *types.MethodSet, name string) {
+	selection := methods.Lookup(g.pkg, name)
+	signature := selection.Obj().Type().(*types.Signature)
+	action, parameters := g.action(signature)
+	g.parameters(parameters)
+	fmt.Fprintf(&g.out, "\t%s = %s,\n", g.name(name, false), action)
+}
+
+// parameters writes a comment listing the parameters of the action that
+// follows, if it has any.
+func (g *generator) parameters(parameters []string) {
+	if len(parameters) > 0 {
+		fmt.Fprintf(&g.out, "\t# parameters: %s\n", strings.Join(parameters, ", "))
+	}
+}
+
+// action renders a method signature as a body-less Cabin action type, and
+// returns its parameters, which the grammar can't write in it, separately. A
+// trailing error result becomes an Attempt with a Text error.
+func (g *generator) action(signature *types.Signature) (action string, parameters []string) {
+	for i := 0; i < signature.Params().Len(); i++ {
+		parameter := signature.Params().At(i)
+		name := fmt.Sprintf("argument_%d", i+1)
+		if parameter.Name() != "" && parameter.Name() != "_" {
+			name = g.name(parameter.Name(), false)
+		}
+		typ := parameter.Type()
+		if signature.Variadic() && i == signature.Params().Len()-1 {
+			typ = types.NewSlice(typ.(*types.Slice).Elem())
+		}
+		parameters = append(parameters, name+": "+g.cabinType(typ))
+	}
+
+	results := signature.Results()
+	var values []types.Type
+	fallible := false
+	for i := 0; i < results.Len(); i++ {
+		if i == results.Len()-1 && isError(results.At(i).Type()) {
+			fallible = true
+			continue
+		}
+		values = append(values, results.At(i).Type())
+	}
+	var result string
+	switch len(values) {
+	case 0:
+	case 1:
+		result = g.cabinType(values[0])
+	default:
+		result = "List<Any>"
+	}
+	switch {
+	case fallible && result == "":
+		return "action: Optional<Text>", parameters
+	case fallible:
+		return "action: Attempt<" + result + ", Text>", parameters
+	case result != "":
+		return "action: " + result, parameters
+	}
+	return "action", parameters
+}
+
+// cabinType converts a Go type to Cabin type syntax. Types without a Cabin
+// equivalent become Any.
+func (g *generator) cabinType(typ types.Type) string {
+	typ = types.Unalias(typ)
+	if isError(typ) {
+		return "Text"
+	}
+	switch typ := typ.(type) {
+	case *types.Basic:
+		switch {
+		case typ.Info()&types.IsString != 0:
+			return "Text"
+		case typ.Info()&types.IsBoolean != 0:
+			return "Boolean"
+		case typ.Info()&types.IsNumeric != 0:
+			return "Number"
+		}
+	case *types.Pointer:
+		return "Optional<" + g.cabinType(typ.Elem()) + ">"
+	case *types.Slice:
+		return "List<" + g.cabinType(typ.Elem()) + ">"
+	case *types.Array:
+		return "List<" + g.cabinType(typ.Elem()) + ">"
+	case *types.TypeParam:
+		return g.name(typ.Obj().Name(), true)
+	case *types.Named:
+		if g.emitted[typ.Obj()] || g.emitted[typ.Origin().Obj()] {
+			arguments := typ.TypeArgs()
+			if arguments.Len() == 0 {
+				return typ.Obj().Name()
+			}
+			var list []string
+			for i := 0; i < arguments.Len(); i++ {
+				list = append(list, g.cabinType(arguments.At(i)))
+			}
+			return typ.Obj().Name() + "<" + strings.Join(list, ", ") + ">"
+		}
+		switch typ.Underlying().(type) {
+		case *types.Basic, *types.Pointer, *types.Slice, *types.Array:
+			return g.cabinType(typ.Underlying())
+		}
+	}
+	return "Any"
+}
+
+func isError(typ types.Type) bool {
+	return types.Identical(typ, types.Universe.Lookup("error").Type())
+}
+
+// SnakeCase converts a Go identifier to a Cabin snake_case name, keeping
+// initialisms together, so that "UserID" becomes "user_id".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
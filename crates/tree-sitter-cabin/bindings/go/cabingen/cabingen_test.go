@@ -0,0 +1,225 @@
+package cabingen_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/cabingen"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const source = `package models
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleViewer Role = "viewer"
+)
+
+type Stringer interface {
+	String() string
+}
+
+type User struct {
+	Name    string   ` + "`json:\"display_name\"`" + `
+	UserID  int
+	Roles   []Role
+	Manager *User
+	Secret  string ` + "`json:\"-\"`" + `
+	hidden  bool
+}
+
+func (u *User) String() string { return u.Name }
+
+func (u *User) Promote(role Role) error { return nil }
+
+func (u *User) Rename(first, last string, tags ...string) (string, int, error) { return "", 0, nil }
+
+type Page[T any] struct {
+	Items []T
+	Next  *Page[T]
+}
+`
+
+const expected = `let visible Role = either {
+	admin,
+	viewer,
+};
+
+let visible Stringer = group {
+	visible string: action: Text,
+};
+
+let visible User = group {
+	visible display_name: Text,
+	visible user_id: Number,
+	visible roles: List<Role>,
+	visible manager: Optional<User>,
+};
+
+let visible Page = group<T: Any> {
+	visible items: List<T>,
+	visible next: Optional<Page<T>>,
+};
+
+let visible UserAsStringer = extend User as Stringer {
+	string = action: Text,
+};
+
+let visible UserMethods = extend User {
+	# parameters: role: Role
+	promote = action: Optional<Text>,
+	# parameters: first: Text, last: Text, tags: List<Text>
+	rename = action: Attempt<List<Any>, Text>,
+};
+
+`
+
+func check(t *testing.T, source string) *types.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", source, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := config.Check("models", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+// parseCabin fails the test if source has a syntax error.
+func parseCabin(t *testing.T, source []byte) {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+	if tree.RootNode().HasError() {
+		t.Errorf("generated Cabin doesn't parse: %s\n%s", tree.RootNode().ToSexp(), source)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	pkg := check(t, source)
+	generated, err := cabingen.Generate(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(generated) != expected {
+		t.Errorf("unexpected output:\n%s", generated)
+	}
+	parseCabin(t, generated)
+}
+
+func TestSnakeCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Name":       "name",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"Base64Data": "base64_data",
+	} {
+		if got := cabingen.SnakeCase(name); got != want {
+			t.Errorf("SnakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateNames(t *testing.T) {
+	pkg := check(t, `package models
+
+type Kind string
+
+const (
+	KindLet Kind = "let"
+	KindRun Kind = "run"
+)
+
+type Step[In any] struct {
+	If    bool
+	Input In
+	Kind  Kind
+	Match string `+"`json:\"match_name\"`"+`
+}
+`)
+	generated, err := cabingen.Generate(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `let visible Kind = either {
+	let_,
+	run_,
+};
+
+let visible Step = group<In: Any> {
+	visible if_: Boolean,
+	visible input: In,
+	visible kind: Kind,
+	visible match_name: Text,
+};
+
+`
+	if string(generated) != want {
+		t.Errorf("unexpected output:\n%s", generated)
+	}
+	parseCabin(t, generated)
+}
+
+func TestGenerateInvalidNames(t *testing.T) {
+	for _, field := range []string{
+		"Name string `json:\"display-name\"`",
+		"Name string `json:\"let\"`",
+		"Größe int",
+	} {
+		pkg := check(t, "package models\n\ntype User struct {\n"+field+"\n}\n")
+		if _, err := cabingen.Generate(pkg); err == nil || !strings.Contains(err.Error(), "isn't a valid Cabin name") {
+			t.Errorf("%s: got error %v, want an invalid name", field, err)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	packages, err := cabingen.Load("testdata/models", ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(packages) != 1 || packages[0].Name() != "models" {
+		t.Fatalf("loaded %v, want the models package", packages)
+	}
+	generated, err := cabingen.Generate(packages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = `let visible Order = group {
+	visible id: Text,
+	visible total: Number,
+	visible lines: List<Line>,
+};
+
+let visible Line = group {
+	visible product: Text,
+	visible quantity: Number,
+};
+
+`
+	if string(generated) != want {
+		t.Errorf("unexpected output:\n%s", generated)
+	}
+	parseCabin(t, generated)
+
+	if _, err := cabingen.Load("testdata/models", "./missing"); err == nil {
+		t.Error("loaded a package that doesn't exist")
+	}
+}
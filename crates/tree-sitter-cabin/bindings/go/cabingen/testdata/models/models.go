@@ -0,0 +1,13 @@
+// Package models is loaded by the tests of cabingen.
+package models
+
+type Order struct {
+	ID    string `json:"id"`
+	Total float64
+	Lines []Line
+}
+
+type Line struct {
+	Product  string
+	Quantity int
+}
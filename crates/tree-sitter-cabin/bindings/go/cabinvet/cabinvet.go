@@ -0,0 +1,428 @@
+// Package cabinvet defines an analyzer that checks the Cabin source embedded
+// in Go packages, so that go vet catches broken Cabin scripts before they're
+// deployed.
+//
+// It checks two kinds of source: string constants and variables marked with
+// a //cabin:source directive in their doc comment, and the .cabin files that
+// a //go:embed directive embeds. The value of a marked declaration may be a
+// string literal or a concatenation of them.
+//
+//	//cabin:source
+//	const greet = `system.terminal.print("hi");`
+//
+//	//go:embed scripts/*.cabin
+//	var scripts embed.FS
+//
+// Syntax errors and the errors and warnings of Rules are reported at their
+// positions in the Go or Cabin file, with their fixes.
+package cabinvet
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+	"golang.org/x/tools/go/analysis"
+)
+
+// Directive marks a string constant or variable as Cabin source.
+const Directive = "//cabin:source"
+
+// Rules are the rules the analyzer checks Cabin source with.
+var Rules = []lint.Rule{
+	lint.Syntax,
+	lint.Comparisons,
+	lint.NegativeLiterals,
+	lint.NumberRanges,
+	lint.Whitespace,
+}
+
+// Analyzer reports the syntax and lint errors of the Cabin source embedded in
+// a Go package.
+var Analyzer = &analysis.Analyzer{
+	Name: "cabin",
+	Doc:  "check Cabin source embedded in Go\n\nThe analyzer parses string constants and variables marked with //cabin:source and the .cabin files embedded with //go:embed, and reports their syntax and lint errors.",
+	Run:  run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	// embedded maps the embedded files to the first directive embedding
+	// them.
+	embedded := map[string]*ast.Comment{}
+	for _, file := range pass.Files {
+		dir := filepath.Dir(pass.Fset.File(file.Pos()).Name())
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.CONST && gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				value := spec.(*ast.ValueSpec)
+				docs := []*ast.CommentGroup{value.Doc}
+				if len(gen.Specs) == 1 {
+					docs = append(docs, gen.Doc)
+				}
+				if hasDirective(docs, Directive) {
+					for _, expr := range value.Values {
+						checkString(pass, expr)
+					}
+				}
+				for _, directive := range embedDirectives(docs) {
+					for _, pattern := range directive.patterns {
+						names, err := embeddedFiles(pass, dir, pattern)
+						if err != nil {
+							pass.Reportf(directive.comment.Pos(), "invalid //go:embed pattern %q: %v", pattern, err)
+						}
+						for _, name := range names {
+							if embedded[name] == nil {
+								embedded[name] = directive.comment
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(embedded))
+	for name := range embedded {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := checkFile(pass, name, embedded[name]); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func hasDirective(docs []*ast.CommentGroup, directive string) bool {
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, comment := range doc.List {
+			if comment.Text == directive || strings.HasPrefix(comment.Text, directive+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// An embedDirective is a //go:embed directive and its patterns.
+type embedDirective struct {
+	comment  *ast.Comment
+	patterns []string
+}
+
+// embedDirectives returns the //go:embed directives in docs.
+func embedDirectives(docs []*ast.CommentGroup) []embedDirective {
+	var directives []embedDirective
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		for _, comment := range doc.List {
+			if args, ok := strings.CutPrefix(comment.Text, "//go:embed "); ok {
+				directives = append(directives, embedDirective{comment, embedArgs(args)})
+			}
+		}
+	}
+	return directives
+}
+
+// embedArgs splits the arguments of a //go:embed directive like the go command
+// does: at spaces, except in Go string literals, which are unquoted. Arguments
+// after one that isn't valid are left out, since the go command reports it.
+func embedArgs(args string) []string {
+	var patterns []string
+	for {
+		args = strings.TrimLeft(args, " \t")
+		if args == "" {
+			return patterns
+		}
+		var pattern string
+		if args[0] == '"' || args[0] == '`' {
+			quoted, err := strconv.QuotedPrefix(args)
+			if err != nil {
+				return patterns
+			}
+			pattern, _ = strconv.Unquote(quoted)
+			args = args[len(quoted):]
+			if args != "" && args[0] != ' ' && args[0] != '\t' {
+				return patterns
+			}
+		} else {
+			end := strings.IndexAny(args, " \t")
+			if end < 0 {
+				end = len(args)
+			}
+			pattern, args = args[:end], args[end:]
+		}
+		patterns = append(patterns, pattern)
+	}
+}
+
+// passFiles returns the non-Go files that the driver of pass provides, which
+// pass.ReadFile can read.
+func passFiles(pass *analysis.Pass) []string {
+	return slices.Concat(pass.OtherFiles, pass.IgnoredFiles)
+}
+
+// embeddedFiles returns the .cabin files that pattern embeds from dir. Like
+// go:embed, files in a directory whose names start with . or _ are left out
+// unless the pattern starts with all:.
+//
+// The files the driver provides are matched first, so that a file that is
+// only in an editor's unsaved buffers is found. Drivers don't list embedded
+// files yet, so the rest are found on disk. The error is that of a malformed
+// pattern.
+func embeddedFiles(pass *analysis.Pass, dir, pattern string) ([]string, error) {
+	pattern, all := strings.CutPrefix(pattern, "all:")
+	var files []string
+	for _, name := range passFiles(pass) {
+		rel, err := filepath.Rel(dir, name)
+		if err == nil && filepath.Ext(name) == ".cabin" && embeds(pattern, filepath.ToSlash(rel), all) {
+			files = append(files, name)
+		}
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil {
+		return files, err
+	}
+	for _, match := range matches {
+		filepath.WalkDir(match, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if hidden := strings.HasPrefix(entry.Name(), ".") || strings.HasPrefix(entry.Name(), "_"); path != match && hidden && !all {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.IsDir() && filepath.Ext(path) == ".cabin" && !slices.Contains(files, path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
+	return files, nil
+}
+
+// embeds reports whether pattern embeds the file at the slash-separated path
+// rel: if it matches the file or one of its directories, and no directory
+// below the match is left out for its name.
+func embeds(pattern, rel string, all bool) bool {
+	parts := strings.Split(rel, "/")
+	for i := range parts {
+		if matched, _ := path.Match(pattern, strings.Join(parts[:i+1], "/")); !matched {
+			continue
+		}
+		for _, part := range parts[i+1:] {
+			if !all && (strings.HasPrefix(part, ".") || strings.HasPrefix(part, "_")) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// readFile reads an embedded file through pass.ReadFile, which sees the
+// driver's overlays, when the driver provides it, and from disk otherwise.
+func readFile(pass *analysis.Pass, name string) ([]byte, error) {
+	if pass.ReadFile != nil && slices.Contains(passFiles(pass), name) {
+		return pass.ReadFile(name)
+	}
+	return os.ReadFile(name)
+}
+
+// checkFile checks the embedded Cabin file name, which directive embeds. A file
+// that can't be read is reported at the directive, so that the other files
+// are still checked.
+func checkFile(pass *analysis.Pass, name string, directive *ast.Comment) error {
+	source, err := readFile(pass, name)
+	if err != nil {
+		// The error of os.ReadFile repeats the name.
+		var pathErr *fs.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		pass.Reportf(directive.Pos(), "can't read embedded Cabin file %s: %v", name, err)
+		return nil
+	}
+	file := pass.Fset.AddFile(name, -1, len(source))
+	file.SetLinesForContent(source)
+	return check(pass, name, source, func(start, end uint) (token.Pos, token.Pos) {
+		return file.Pos(int(start)), file.Pos(int(end))
+	}, func(edit lint.Edit) (analysis.TextEdit, bool) {
+		return analysis.TextEdit{Pos: file.Pos(int(edit.Start)), End: file.Pos(int(edit.End)), NewText: []byte(edit.Text)}, true
+	})
+}
+
+// checkString checks the Cabin source in the string literals of expr.
+func checkString(pass *analysis.Pass, expr ast.Expr) {
+	var source stringSource
+	if !source.add(expr) {
+		pass.Reportf(expr.Pos(), "Cabin source must be a string literal or a concatenation of them")
+		return
+	}
+	check(pass, pass.Fset.Position(expr.Pos()).Filename, source.text, source.span, source.edit)
+}
+
+// check reports the diagnostics of source, whose spans are converted to
+// positions with span, and whose fixes are converted with edit where
+// possible.
+func check(pass *analysis.Pass, name string, source []byte, span func(start, end uint) (token.Pos, token.Pos), edit func(lint.Edit) (analysis.TextEdit, bool)) error {
+	file, err := lint.Parse(name, source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for _, diagnostic := range lint.Run(file, Rules...) {
+		if diagnostic.Severity > lint.Warning {
+			continue
+		}
+		report := analysis.Diagnostic{
+			Category: diagnostic.Rule,
+			Message:  fmt.Sprintf("%s (%s)", diagnostic.Message, diagnostic.Rule),
+		}
+		report.Pos, report.End = span(diagnostic.Range.StartByte, diagnostic.Range.EndByte)
+		if diagnostic.Fix != nil {
+			fix := analysis.SuggestedFix{Message: diagnostic.Fix.Message}
+			for _, e := range diagnostic.Fix.Edits {
+				textEdit, ok := edit(e)
+				if !ok {
+					fix.TextEdits = nil
+					break
+				}
+				fix.TextEdits = append(fix.TextEdits, textEdit)
+			}
+			if fix.TextEdits != nil {
+				report.SuggestedFixes = []analysis.SuggestedFix{fix}
+			}
+		}
+		pass.Report(report)
+	}
+	return nil
+}
+
+// A stringSource is Cabin source from Go string literals. For each byte of
+// it, it keeps the literal it's in and the positions of the character or
+// escape sequence that it's part of.
+type stringSource struct {
+	text       []byte
+	literals   []*ast.BasicLit
+	literal    []int
+	start, end []token.Pos
+}
+
+// add appends the values of the string literals of expr, and reports whether
+// it's a string literal or a concatenation of them.
+func (s *stringSource) add(expr ast.Expr) bool {
+	var lit *ast.BasicLit
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return s.add(expr.X)
+	case *ast.BinaryExpr:
+		return expr.Op == token.ADD && s.add(expr.X) && s.add(expr.Y)
+	case *ast.BasicLit:
+		if expr.Kind != token.STRING {
+			return false
+		}
+		lit = expr
+	default:
+		return false
+	}
+
+	index := len(s.literals)
+	s.literals = append(s.literals, lit)
+	appendBytes := func(b []byte, start, end int) {
+		for _, b := range b {
+			s.text = append(s.text, b)
+			s.literal = append(s.literal, index)
+			s.start = append(s.start, lit.Pos()+token.Pos(start))
+			s.end = append(s.end, lit.Pos()+token.Pos(end))
+		}
+	}
+
+	value := lit.Value[1 : len(lit.Value)-1]
+	if lit.Value[0] == '`' {
+		for i := 0; i < len(value); i++ {
+			// Carriage returns are dropped from raw strings.
+			if value[i] != '\r' {
+				appendBytes([]byte{value[i]}, i+1, i+2)
+			}
+		}
+		return true
+	}
+	for rest := value; rest != ""; {
+		r, multibyte, tail, err := strconv.UnquoteChar(rest, '"')
+		if err != nil {
+			return false
+		}
+		start, end := len(value)-len(rest)+1, len(value)-len(tail)+1
+		if multibyte || r < utf8.RuneSelf {
+			appendBytes(utf8.AppendRune(nil, r), start, end)
+		} else {
+			appendBytes([]byte{byte(r)}, start, end)
+		}
+		rest = tail
+	}
+	return true
+}
+
+// span returns the positions of the bytes of the source from start to end.
+func (s *stringSource) span(start, end uint) (token.Pos, token.Pos) {
+	if start == end {
+		pos := s.insertion(start)
+		return pos, pos
+	}
+	return s.start[start], s.end[end-1]
+}
+
+// insertion returns the position to insert text before byte i at, which is
+// the end of the last literal when i is the end of the source.
+func (s *stringSource) insertion(i uint) token.Pos {
+	if i < uint(len(s.text)) {
+		return s.start[i]
+	}
+	return s.literals[len(s.literals)-1].End() - 1
+}
+
+// edit converts an edit of the source to an edit of the literal it's in, if
+// it's in a single one.
+func (s *stringSource) edit(edit lint.Edit) (analysis.TextEdit, bool) {
+	index := len(s.literals) - 1
+	if edit.Start < uint(len(s.text)) {
+		index = s.literal[edit.Start]
+	}
+	if edit.End > edit.Start && s.literal[edit.End-1] != index {
+		return analysis.TextEdit{}, false
+	}
+	text := edit.Text
+	if s.literals[index].Value[0] == '`' {
+		if strings.ContainsAny(text, "`\r") {
+			return analysis.TextEdit{}, false
+		}
+	} else {
+		quoted := strconv.Quote(text)
+		text = quoted[1 : len(quoted)-1]
+	}
+	pos, end := s.span(edit.Start, edit.End)
+	return analysis.TextEdit{Pos: pos, End: end, NewText: []byte(text)}, true
+}
@@ -0,0 +1,125 @@
+package cabinvet_test
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/cabinvet"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestStrings(t *testing.T) {
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), cabinvet.Analyzer, "a")
+}
+
+// recorder collects the errors of analysistest, which can't read
+// expectations from Cabin files, so the diagnostics in them are all
+// unexpected.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestEmbeddedFiles(t *testing.T) {
+	var r recorder
+	analysistest.Run(&r, analysistest.TestData(), cabinvet.Analyzer, "b")
+	want := []string{
+		"b/scripts/main.cabin:2:9: unexpected diagnostic: x -1 is a subtraction, not x followed by a negative number (negative-literal)",
+		`b/scripts/main.cabin:3:1: unexpected diagnostic: unexpected "let" (syntax)`,
+	}
+	if strings.Join(r.errors, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(r.errors, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	// The driver provides files that aren't on disk, like an editor's
+	// unsaved buffers.
+	dir := t.TempDir()
+	files := map[string]string{
+		dir + "/scripts/main.cabin":        "let x = y -1;\n",
+		dir + "/scripts/_drafts/old.cabin": "let x = y -1;\n",
+		dir + "/other/main.cabin":          "let x = y -1;\n",
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, dir+"/c.go", "package c\n\nimport \"embed\"\n\n//go:embed scripts\nvar scripts embed.FS\n", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	pass := &analysis.Pass{
+		Analyzer:   cabinvet.Analyzer,
+		Fset:       fset,
+		Files:      []*ast.File{file},
+		OtherFiles: []string{dir + "/scripts/main.cabin", dir + "/scripts/_drafts/old.cabin", dir + "/other/main.cabin"},
+		ReadFile: func(name string) ([]byte, error) {
+			if source, ok := files[name]; ok {
+				return []byte(source), nil
+			}
+			return nil, os.ErrNotExist
+		},
+		Report: func(diagnostic analysis.Diagnostic) {
+			position := fset.Position(diagnostic.Pos)
+			got = append(got, fmt.Sprintf("%s:%d:%d", strings.TrimPrefix(position.Filename, dir+"/"), position.Line, position.Column))
+		},
+	}
+	if _, err := cabinvet.Analyzer.Run(pass); err != nil {
+		t.Fatal(err)
+	}
+	if want := "scripts/main.cabin:1:9"; strings.Join(got, "\n") != want {
+		t.Errorf("got diagnostics at\n%s\nwant %s", strings.Join(got, "\n"), want)
+	}
+}
+
+func TestEmbedDirectives(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		dir + "/my scripts/main.cabin": "let x = y -1;\n",
+		dir + "/tab/main.cabin":        "let x = y -1;\n",
+	}
+	source := "package c\n\nimport \"embed\"\n\n//go:embed \"my scripts/*.cabin\" `tab/*.cabin` gone.cabin\nvar scripts embed.FS\n\n//go:embed broken/[.cabin\nvar broken embed.FS\n"
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, dir+"/c.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	pass := &analysis.Pass{
+		Analyzer:   cabinvet.Analyzer,
+		Fset:       fset,
+		Files:      []*ast.File{file},
+		OtherFiles: []string{dir + "/my scripts/main.cabin", dir + "/tab/main.cabin", dir + "/gone.cabin"},
+		ReadFile: func(name string) ([]byte, error) {
+			if source, ok := files[name]; ok {
+				return []byte(source), nil
+			}
+			return nil, os.ErrNotExist
+		},
+		Report: func(diagnostic analysis.Diagnostic) {
+			position := fset.Position(diagnostic.Pos)
+			message := strings.ReplaceAll(diagnostic.Message, dir+"/", "")
+			got = append(got, fmt.Sprintf("%s:%d:%d: %s", strings.TrimPrefix(position.Filename, dir+"/"), position.Line, position.Column, message))
+		},
+	}
+	if _, err := cabinvet.Analyzer.Run(pass); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`c.go:8:1: invalid //go:embed pattern "broken/[.cabin": syntax error in pattern`,
+		"c.go:5:1: can't read embedded Cabin file gone.cabin: file does not exist",
+		"my scripts/main.cabin:1:9: y -1 is a subtraction, not y followed by a negative number (negative-literal)",
+		"tab/main.cabin:1:9: y -1 is a subtraction, not y followed by a negative number (negative-literal)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
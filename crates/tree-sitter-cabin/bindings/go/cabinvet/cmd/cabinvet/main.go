@@ -0,0 +1,12 @@
+// Command cabinvet checks the Cabin source embedded in Go packages. It runs
+// on its own, or as go vet -vettool=$(which cabinvet).
+package main
+
+import (
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/cabinvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(cabinvet.Analyzer)
+}
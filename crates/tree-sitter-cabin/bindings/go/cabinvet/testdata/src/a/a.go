@@ -0,0 +1,21 @@
+package a
+
+//cabin:source
+const greet = `system.terminal.print("hi")` // want `missing ;`
+
+//cabin:source
+const spaced = "let x =\u00a01;" // want `invisible character U\+00A0`
+
+const (
+	//cabin:source
+	joined = "let a = 1;\n" +
+		`let b = 2` // want `missing ;`
+
+	unmarked = `let x =`
+)
+
+//cabin:source
+const fine = `let x = 1;`
+
+//cabin:source
+var notLiteral = greet + "" // want `Cabin source must be a string literal`
@@ -0,0 +1,9 @@
+package b
+
+import "embed"
+
+//go:embed scripts
+var scripts embed.FS
+
+//go:embed scripts/main.cabin
+var main string
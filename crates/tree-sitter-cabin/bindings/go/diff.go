@@ -0,0 +1,219 @@
+package tree_sitter_cabin
+
+import (
+	"bytes"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxDiffLines bounds the number of changed lines Diff looks for. Beyond it,
+// everything between the first and the last change becomes a single edit.
+const maxDiffLines = 1000
+
+// Diff returns the edits that turn oldText into newText, for a client that
+// sends the whole text of a document instead of what changed. Applying them
+// to the tree of oldText with Tree.Edit lets the parser reuse the unchanged
+// parts when it parses newText.
+//
+// The changed lines are found with Myers' diff algorithm, and each run of
+// them is narrowed down to the bytes that differ. The edits are in
+// descending order of position, so that the positions of each one are still
+// valid after applying the ones before it.
+func Diff(oldText, newText []byte) []tree_sitter.InputEdit {
+	prefix := commonPrefix(oldText, newText)
+	suffix := commonSuffix(oldText[prefix:], newText[prefix:])
+	if prefix == len(oldText) && prefix == len(newText) {
+		return nil
+	}
+
+	// Diff whole lines, starting at the line the first change is on.
+	start := bytes.LastIndexByte(oldText[:prefix], '\n') + 1
+	oldMiddle, newMiddle := oldText[start:len(oldText)-suffix], newText[start:len(newText)-suffix]
+	oldLines, newLines := splitLines(oldMiddle), splitLines(newMiddle)
+	matches, ok := diffLines(oldLines, newLines)
+	if !ok {
+		matches = nil
+	}
+
+	var edits []tree_sitter.InputEdit
+	points := pointScanner{text: oldText}
+	oldStart, newStart := start, start
+	i, j := 0, 0
+	for _, match := range append(matches, [2]int{len(oldLines), len(newLines)}) {
+		oldEnd, newEnd := oldStart, newStart
+		for ; i < match[0]; i++ {
+			oldEnd += len(oldLines[i])
+		}
+		for ; j < match[1]; j++ {
+			newEnd += len(newLines[j])
+		}
+		if oldEnd > oldStart || newEnd > newStart {
+			edits = append(edits, hunkEdit(oldText, newText, oldStart, oldEnd, newStart, newEnd, &points))
+		}
+		if match[0] < len(oldLines) {
+			oldEnd += len(oldLines[i])
+			newEnd += len(newLines[j])
+			i, j = i+1, j+1
+		}
+		oldStart, newStart = oldEnd, newEnd
+	}
+
+	for left, right := 0, len(edits)-1; left < right; left, right = left+1, right-1 {
+		edits[left], edits[right] = edits[right], edits[left]
+	}
+	return edits
+}
+
+// hunkEdit returns the edit that replaces oldText[oldStart:oldEnd] with
+// newText[newStart:newEnd], without the bytes they start or end with in
+// common.
+func hunkEdit(oldText, newText []byte, oldStart, oldEnd, newStart, newEnd int, points *pointScanner) tree_sitter.InputEdit {
+	prefix := commonPrefix(oldText[oldStart:oldEnd], newText[newStart:newEnd])
+	oldStart, newStart = oldStart+prefix, newStart+prefix
+	suffix := commonSuffix(oldText[oldStart:oldEnd], newText[newStart:newEnd])
+	oldEnd, newEnd = oldEnd-suffix, newEnd-suffix
+
+	startPosition := points.at(oldStart)
+	return tree_sitter.InputEdit{
+		StartByte:      uint(oldStart),
+		OldEndByte:     uint(oldEnd),
+		NewEndByte:     uint(oldStart + newEnd - newStart),
+		StartPosition:  startPosition,
+		OldEndPosition: points.at(oldEnd),
+		NewEndPosition: advance(startPosition, newText[newStart:newEnd]),
+	}
+}
+
+// A pointScanner converts increasing byte offsets in text to points.
+type pointScanner struct {
+	text   []byte
+	offset int
+	point  tree_sitter.Point
+}
+
+func (s *pointScanner) at(offset int) tree_sitter.Point {
+	s.point = advance(s.point, s.text[s.offset:offset])
+	s.offset = offset
+	return s.point
+}
+
+// advance returns the point after text, if it starts at point.
+func advance(point tree_sitter.Point, text []byte) tree_sitter.Point {
+	if newlines := bytes.Count(text, []byte{'\n'}); newlines > 0 {
+		return tree_sitter.NewPoint(point.Row+uint(newlines), uint(len(text)-bytes.LastIndexByte(text, '\n')-1))
+	}
+	return tree_sitter.NewPoint(point.Row, point.Column+uint(len(text)))
+}
+
+func commonPrefix(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+func commonSuffix(a, b []byte) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[len(a)-1-i] != b[len(b)-1-i] {
+			return i
+		}
+	}
+	return n
+}
+
+// splitLines splits text after each line break.
+func splitLines(text []byte) [][]byte {
+	lines := bytes.SplitAfter(text, []byte{'\n'})
+	if len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the pairs of indices of the lines that a shortest edit
+// script from a to b keeps, in order, using Myers' algorithm. It gives up if
+// more than maxDiffLines lines change.
+func diffLines(a, b [][]byte) ([][2]int, bool) {
+	ids := map[string]int{}
+	id := func(lines [][]byte) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			if _, ok := ids[string(line)]; !ok {
+				ids[string(line)] = len(ids)
+			}
+			out[i] = ids[string(line)]
+		}
+		return out
+	}
+	x, y := id(a), id(b)
+	n, m := len(x), len(y)
+
+	// v[offset+k] is the furthest x reached on diagonal k. trace[d] keeps
+	// the diagonals -d+1 to d-1 of v as they were before step d, for
+	// backtracking.
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= min(n+m, maxDiffLines); d++ {
+		if d > 0 {
+			trace = append(trace, append([]int(nil), v[offset-d+1:offset+d]...))
+		} else {
+			trace = append(trace, nil)
+		}
+		for k := -d; k <= d; k += 2 {
+			var i int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				i = v[offset+k+1]
+			} else {
+				i = v[offset+k-1] + 1
+			}
+			j := i - k
+			for i < n && j < m && x[i] == y[j] {
+				i, j = i+1, j+1
+			}
+			v[offset+k] = i
+			if i >= n && j >= m {
+				return backtrack(trace, n, m), true
+			}
+		}
+	}
+	return nil, false
+}
+
+func backtrack(trace [][]int, i, j int) [][2]int {
+	var matches [][2]int
+	for d := len(trace) - 1; d > 0; d-- {
+		previous := func(k int) int { return trace[d][k+d-1] }
+		k := i - j
+		var previousK int
+		if k == -d || k != d && previous(k-1) < previous(k+1) {
+			previousK = k + 1
+		} else {
+			previousK = k - 1
+		}
+		previousI := previous(previousK)
+		// The step inserts a line of b going down a diagonal, or deletes a
+		// line of a going up one, and the lines after it up to (i, j) match.
+		stepI := previousI
+		if previousK == k-1 {
+			stepI++
+		}
+		for i > stepI {
+			i, j = i-1, j-1
+			matches = append(matches, [2]int{i, j})
+		}
+		i, j = previousI, previousI-previousK
+	}
+	for i > 0 && j > 0 {
+		i, j = i-1, j-1
+		matches = append(matches, [2]int{i, j})
+	}
+	for left, right := 0, len(matches)-1; left < right; left, right = left+1, right-1 {
+		matches[left], matches[right] = matches[right], matches[left]
+	}
+	return matches
+}
@@ -0,0 +1,136 @@
+package tree_sitter_cabin_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		old, new string
+		want     []string
+	}{
+		{"let x = 1;", "let x = 1;", nil},
+		{"let x = 1;", "let x = 2;", []string{"[8-9) -> [8-9)"}},
+		{"", "let x = 1;\n", []string{"[0-0) -> [0-11)"}},
+		{"let x = 1;\n", "", []string{"[0-11) -> [0-0)"}},
+		{
+			"let a = 1;\nlet b = 2;\nlet c = 3;\nlet d = 4;\n",
+			"let a = 10;\nlet b = 2;\nlet c = 3;\nlet e = 4;\n",
+			[]string{"[37-38) -> [37-38)", "[9-9) -> [9-10)"},
+		},
+		{
+			"let a = 1;\nlet b = 2;\nlet c = 3;\n",
+			"let b = 2;\nlet c = 3;\nlet d = 4;\n",
+			[]string{"[31-31) -> [31-42)", "[0-11) -> [0-0)"},
+		},
+		{"let x = \"a\nb\";\n", "let x = \"a\nc\nb\";\n", []string{"[11-11) -> [11-13)"}},
+	} {
+		edits := tree_sitter_cabin.Diff([]byte(test.old), []byte(test.new))
+		var got []string
+		for _, edit := range edits {
+			got = append(got, fmt.Sprintf("[%d-%d) -> [%d-%d)", edit.StartByte, edit.OldEndByte, edit.StartByte, edit.NewEndByte))
+		}
+		if strings.Join(got, " ") != strings.Join(test.want, " ") {
+			t.Errorf("Diff(%q, %q) = %v, want %v", test.old, test.new, got, test.want)
+		}
+		checkEdits(t, test.old, test.new, edits)
+	}
+}
+
+// checkEdits checks that edits turn old into new when each inserts the text
+// of new it spans, and that their points match their offsets.
+func checkEdits(t *testing.T, old, new string, edits []tree_sitter.InputEdit) {
+	t.Helper()
+	// The edits before an edit in the text come after it in edits, and
+	// shift where the text it inserts is in new.
+	inserted := make([]string, len(edits))
+	shift := 0
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		if i < len(edits)-1 && edit.StartByte < edits[i+1].OldEndByte {
+			t.Errorf("edit %d overlaps the edit after it", i)
+			return
+		}
+		start := int(edit.StartByte) + shift
+		inserted[i] = new[start : start+int(edit.NewEndByte-edit.StartByte)]
+		shift += int(edit.NewEndByte) - int(edit.OldEndByte)
+	}
+
+	text := old
+	for i, edit := range edits {
+		if point(text, edit.StartByte) != edit.StartPosition || point(text, edit.OldEndByte) != edit.OldEndPosition {
+			t.Errorf("edit %d spans %v to %v, want %v to %v", i, edit.StartPosition, edit.OldEndPosition, point(text, edit.StartByte), point(text, edit.OldEndByte))
+		}
+		text = text[:edit.StartByte] + inserted[i] + text[edit.OldEndByte:]
+		if point(text, edit.NewEndByte) != edit.NewEndPosition {
+			t.Errorf("edit %d ends at %v, want %v", i, edit.NewEndPosition, point(text, edit.NewEndByte))
+		}
+	}
+	if text != new {
+		t.Errorf("edits turn %q into %q, want %q", old, text, new)
+	}
+}
+
+func point(text string, offset uint) tree_sitter.Point {
+	before := text[:offset]
+	return tree_sitter.NewPoint(uint(strings.Count(before, "\n")), uint(len(before)-strings.LastIndexByte(before, '\n')-1))
+}
+
+func TestDiffReparse(t *testing.T) {
+	var old, new bytes.Buffer
+	for i := range 200 {
+		fmt.Fprintf(&old, "let x%d = %d;\n", i, i)
+		switch {
+		case i%50 == 7:
+			fmt.Fprintf(&new, "let x%d = %d + 1;\n", i, i)
+		case i%50 == 20:
+		case i%50 == 33:
+			fmt.Fprintf(&new, "let x%d = %d;\nlet y%d = x%d;\n", i, i, i, i)
+		default:
+			fmt.Fprintf(&new, "let x%d = %d;\n", i, i)
+		}
+	}
+
+	edits := tree_sitter_cabin.Diff(old.Bytes(), new.Bytes())
+	if len(edits) != 12 {
+		t.Errorf("got %d edits, want 12", len(edits))
+	}
+	checkEdits(t, old.String(), new.String(), edits)
+
+	tree := parse(t, old.String())
+	for _, edit := range edits {
+		tree.Edit(&edit)
+	}
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	reparsed := parser.Parse(new.Bytes(), tree)
+	defer reparsed.Close()
+	if got, want := reparsed.RootNode().ToSexp(), parse(t, new.String()).RootNode().ToSexp(); got != want {
+		t.Errorf("reparsed to\n%s\nwant\n%s", got, want)
+	}
+	if changed := tree.ChangedRanges(reparsed); len(changed) > len(edits) {
+		t.Errorf("%d ranges changed, want at most %d", len(changed), len(edits))
+	}
+}
+
+func TestDiffLimit(t *testing.T) {
+	var old, new strings.Builder
+	for i := range 3000 {
+		fmt.Fprintf(&old, "let x%d = %d;\n", i, i)
+		fmt.Fprintf(&new, "let y%d = %d;\n", i, i)
+	}
+	edits := tree_sitter_cabin.Diff([]byte(old.String()), []byte(new.String()))
+	if len(edits) != 1 {
+		t.Errorf("got %d edits past the limit, want 1", len(edits))
+	}
+	checkEdits(t, old.String(), new.String(), edits)
+}
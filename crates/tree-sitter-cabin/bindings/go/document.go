@@ -0,0 +1,157 @@
+package tree_sitter_cabin
+
+import (
+	"fmt"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A Document is the text of a Cabin file being edited, and its syntax tree.
+//
+// The text is kept in a rope, so edits and conversions between offsets and
+// points take logarithmic time however large the document is, and the parser
+// reads it a piece at a time instead of as one copy. The tree is parsed
+// again incrementally when it's asked for after an edit.
+type Document struct {
+	parser *tree_sitter.Parser
+	text   *rope
+	tree   *tree_sitter.Tree
+	edited bool
+}
+
+// NewDocument returns a document with a copy of text. It must be closed.
+func NewDocument(text []byte) (*Document, error) {
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(Language())); err != nil {
+		parser.Close()
+		return nil, err
+	}
+	return &Document{parser: parser, text: buildRope(text)}, nil
+}
+
+// Close frees the parser and the syntax tree of the document.
+func (d *Document) Close() {
+	if d.tree != nil {
+		d.tree.Close()
+	}
+	d.parser.Close()
+}
+
+// Len returns the length of the text in bytes.
+func (d *Document) Len() uint {
+	return uint(d.text.len())
+}
+
+// LineCount returns the number of lines of the text, which is one more than
+// the number of line breaks.
+func (d *Document) LineCount() uint {
+	if d.text == nil {
+		return 1
+	}
+	return uint(d.text.newlines) + 1
+}
+
+// Bytes returns a copy of the text.
+func (d *Document) Bytes() []byte {
+	return d.text.appendTo(make([]byte, 0, d.text.len()), 0, d.text.len())
+}
+
+// Slice returns a copy of the text from start to end, which must be in the
+// document.
+func (d *Document) Slice(start, end uint) []byte {
+	return d.text.appendTo(nil, int(start), int(min(end, d.Len())))
+}
+
+// Line returns the text of line row, without its line break, or nil if
+// there's no such line.
+func (d *Document) Line(row uint) []byte {
+	start := d.text.lineStart(int(row))
+	if start < 0 {
+		return nil
+	}
+	end := d.text.lineStart(int(row) + 1)
+	if end < 0 {
+		end = d.text.len()
+	} else {
+		end--
+	}
+	return d.text.appendTo([]byte{}, start, end)
+}
+
+// Point returns the row and byte column of offset.
+func (d *Document) Point(offset uint) tree_sitter.Point {
+	offset = min(offset, d.Len())
+	row := d.text.newlinesBefore(int(offset))
+	return tree_sitter.NewPoint(uint(row), offset-uint(d.text.lineStart(row)))
+}
+
+// Offset returns the byte offset of point. A column past the end of its line
+// is the end of the line, and a row past the last line is the end of the
+// text.
+func (d *Document) Offset(point tree_sitter.Point) uint {
+	start := d.text.lineStart(int(point.Row))
+	if start < 0 {
+		return d.Len()
+	}
+	end := d.text.lineStart(int(point.Row) + 1)
+	if end < 0 {
+		end = d.text.len()
+	} else {
+		end--
+	}
+	return uint(min(start+int(point.Column), end))
+}
+
+// Edit replaces the text from start to end with text.
+func (d *Document) Edit(start, end uint, text []byte) error {
+	if start > end || end > d.Len() {
+		return fmt.Errorf("edit [%d, %d) is outside the document of %d bytes", start, end, d.Len())
+	}
+	edit := tree_sitter.InputEdit{
+		StartByte:      start,
+		OldEndByte:     end,
+		NewEndByte:     start + uint(len(text)),
+		StartPosition:  d.Point(start),
+		OldEndPosition: d.Point(end),
+	}
+	edit.NewEndPosition = advance(edit.StartPosition, text)
+
+	before, rest := split(d.text, int(start))
+	_, after := split(rest, int(end-start))
+	d.text = join(join(before, buildRope(text)), after)
+	if d.tree != nil {
+		d.tree.Edit(&edit)
+		d.edited = true
+	}
+	return nil
+}
+
+// Replace replaces the whole text with text, for clients that send all of
+// it. The tree is edited where the text differs, so that it can still be
+// parsed incrementally.
+func (d *Document) Replace(text []byte) {
+	if d.tree != nil {
+		for _, edit := range Diff(d.Bytes(), text) {
+			d.tree.Edit(&edit)
+			d.edited = true
+		}
+	}
+	d.text = buildRope(text)
+}
+
+// Tree returns the syntax tree of the document, parsing it if it has been
+// edited since it was last parsed. The tree belongs to the document, and is
+// only valid until the next call to Tree after an edit, or to Close.
+func (d *Document) Tree() *tree_sitter.Tree {
+	if d.tree != nil && !d.edited {
+		return d.tree
+	}
+	tree := d.parser.ParseWithOptions(func(offset int, _ tree_sitter.Point) []byte {
+		return d.text.chunk(offset)
+	}, d.tree, nil)
+	if d.tree != nil {
+		d.tree.Close()
+	}
+	d.tree, d.edited = tree, false
+	return tree
+}
@@ -0,0 +1,139 @@
+package tree_sitter_cabin_test
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestDocument(t *testing.T) {
+	var text []byte
+	for i := range 2000 {
+		text = fmt.Appendf(text, "let x%d = group { a: Number };\n", i)
+	}
+	document, err := tree_sitter_cabin.NewDocument(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer document.Close()
+	document.Tree()
+
+	r := rand.New(rand.NewSource(1))
+	for i := range 500 {
+		start := uint(r.Intn(len(text) + 1))
+		end := min(start+uint(r.Intn(40)), uint(len(text)))
+		insert := []byte(fmt.Sprintf("let y%d = %d;\n", i, i)[:r.Intn(12)])
+		if err := document.Edit(start, end, insert); err != nil {
+			t.Fatal(err)
+		}
+		text = append(text[:start:start], append(insert, text[end:]...)...)
+		if i%50 == 0 {
+			if got, want := document.Tree().RootNode().ToSexp(), parse(t, string(text)).RootNode().ToSexp(); got != want {
+				t.Fatalf("after edit %d, reparsed to\n%s\nwant\n%s", i, got, want)
+			}
+		}
+	}
+	if !bytes.Equal(document.Bytes(), text) {
+		t.Fatal("edited text differs")
+	}
+	if got, want := document.LineCount(), uint(bytes.Count(text, []byte{'\n'})+1); got != want {
+		t.Errorf("LineCount() = %d, want %d", got, want)
+	}
+
+	lines := bytes.Split(text, []byte{'\n'})
+	offset := uint(0)
+	for row, line := range lines {
+		if got := document.Line(uint(row)); !bytes.Equal(got, line) {
+			t.Fatalf("Line(%d) = %q, want %q", row, got, line)
+		}
+		for column := uint(0); column <= uint(len(line)); column += 7 {
+			point := tree_sitter.NewPoint(uint(row), column)
+			if got := document.Point(offset + column); got != point {
+				t.Fatalf("Point(%d) = %v, want %v", offset+column, got, point)
+			}
+			if got := document.Offset(point); got != offset+column {
+				t.Fatalf("Offset(%v) = %d, want %d", point, got, offset+column)
+			}
+		}
+		offset += uint(len(line)) + 1
+	}
+	if document.Line(uint(len(lines))) != nil {
+		t.Error("Line past the end isn't nil")
+	}
+	if got := document.Offset(tree_sitter.NewPoint(0, 1000)); got != uint(len(lines[0])) {
+		t.Errorf("Offset past the end of a line = %d, want %d", got, len(lines[0]))
+	}
+	if got := document.Slice(10, 20); !bytes.Equal(got, text[10:20]) {
+		t.Errorf("Slice(10, 20) = %q, want %q", got, text[10:20])
+	}
+	if err := document.Edit(0, document.Len()+1, nil); err == nil {
+		t.Error("edit past the end succeeded")
+	}
+}
+
+func TestDocumentReplace(t *testing.T) {
+	document, err := tree_sitter_cabin.NewDocument([]byte("let a = 1;\nlet b = 2;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer document.Close()
+	document.Tree()
+
+	source := "let a = 10;\nlet b = 2;\nlet c = a;\n"
+	document.Replace([]byte(source))
+	if string(document.Bytes()) != source {
+		t.Errorf("replaced text is %q, want %q", document.Bytes(), source)
+	}
+	if got, want := document.Tree().RootNode().ToSexp(), parse(t, source).RootNode().ToSexp(); got != want {
+		t.Errorf("reparsed to\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDocumentTyping(t *testing.T) {
+	d, err := tree_sitter_cabin.NewDocument(bytes.Repeat([]byte("let x = 1;\n"), 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	want := d.Bytes()
+
+	// Type a line one character at a time in a few places, as an editor
+	// sends it.
+	r := rand.New(rand.NewSource(1))
+	for range 20 {
+		offset := uint(r.Intn(int(d.Len())))
+		for i, c := range []byte("let y = 2;\n") {
+			if err := d.Edit(offset+uint(i), offset+uint(i), []byte{c}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want = append(want[:offset:offset], append([]byte("let y = 2;\n"), want[offset:]...)...)
+	}
+	// And delete a character at a time.
+	for range 200 {
+		offset := uint(r.Intn(int(d.Len())))
+		if err := d.Edit(offset, offset+1, nil); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want[:offset:offset], want[offset+1:]...)
+	}
+	if !bytes.Equal(d.Bytes(), want) {
+		t.Fatal("the text of the document is wrong after typing")
+	}
+
+	// No two neighbouring leaves fit in one, so leaves are half full on
+	// average.
+	leaves := tree_sitter_cabin.Leaves(d)
+	for i := 1; i < len(leaves); i++ {
+		if leaves[i-1]+leaves[i] <= tree_sitter_cabin.MaxLeaf {
+			t.Fatalf("leaves %d and %d of %v could be merged", i-1, i, leaves)
+		}
+	}
+	if average := int(d.Len()) / len(leaves); average < tree_sitter_cabin.MaxLeaf/2 {
+		t.Errorf("leaves hold %d bytes on average: %v", average, leaves)
+	}
+}
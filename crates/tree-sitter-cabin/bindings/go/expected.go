@@ -0,0 +1,269 @@
+package tree_sitter_cabin
+
+import (
+	"slices"
+	"sort"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A Token is a terminal symbol of the grammar. Named tokens, such as number
+// or other_identifier, stand for a class of text; anonymous ones, such as
+// "let" or "=>", for exactly their name.
+type Token struct {
+	Symbol uint16
+	Name   string
+	Named  bool
+}
+
+// ExpectedTokens returns the tokens that the grammar allows at pos, given the
+// text before it. The parse state is the one the parser was in after the last
+// token that ends at or before pos, so a partially typed word at pos is
+// ignored.
+//
+// The lookahead set of an LR parse state is shared by every place the state
+// is reached from, so it has tokens that are only allowed after reductions
+// made elsewhere: after the name in let x = 1;, it has ) and => too. Tokens
+// that none of the states the parser reduces the text before pos to can
+// shift are left out. Inside an ERROR node, such as in an unfinished file
+// that ends at pos, those states can't be followed, and the whole lookahead
+// set is returned.
+//
+// The tokens are sorted with anonymous ones first, then by name. The result is
+// nil if the state can't be recovered from a tree mangled by error recovery.
+func ExpectedTokens(tree *tree_sitter.Tree, pos tree_sitter.Point) []Token {
+	language := tree.Language()
+	state := uint16(1)
+	last, next := tokensAround(tree.RootNode(), pos)
+	if last != nil {
+		// The leaf after the last token was lexed in the state the parser
+		// was in after shifting it, if the parser got that far.
+		if next != nil && next.ParseState() != 0 && next.ParseState() != noState {
+			state = next.ParseState()
+		} else {
+			state = stateAfter(language, last)
+		}
+		// Failing that, the tokens allowed after the nodes that the last
+		// token completes are allowed after it too.
+		for node := last; state == 0 && isLastChild(node); {
+			node = node.Parent()
+			state = stateAfter(language, node)
+		}
+	}
+	if state == 0 {
+		return nil
+	}
+	reduced := reducedStates(language, state, last)
+
+	iterator := language.LookaheadIterator(state)
+	if iterator == nil {
+		return nil
+	}
+	defer iterator.Close()
+
+	// Terminals are numbered before the rules, the first of which is
+	// source_file. The lookahead iterator also yields the rules that the
+	// state can go to after a reduction, and the extras, which can be shifted
+	// in any state without leaving it. Comments are extras too, but they're a
+	// rule, so the token that starts one is left out by name.
+	rules := language.IdForNodeKind("source_file", true)
+	var tokens []Token
+	seen := map[string]bool{}
+	for _, symbol := range iterator.Iter() {
+		name := language.NodeKindForId(symbol)
+		named := language.NodeKindIsNamed(symbol)
+		switch {
+		case symbol == 0, symbol >= rules, !language.NodeKindIsVisible(symbol), seen[name], name == commentStart:
+			continue
+		case language.NextState(state, symbol) == state:
+			continue
+		case reduced != nil && !slices.ContainsFunc(reduced, func(s uint16) bool { return language.NextState(s, symbol) != 0 }):
+			continue
+		}
+		seen[name] = true
+		tokens = append(tokens, Token{Symbol: symbol, Name: name, Named: named})
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].Named != tokens[j].Named {
+			return !tokens[i].Named
+		}
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens
+}
+
+// reducedStates returns state, the parse state after last, followed by the
+// states the parser can go to as it reduces the nodes that last completes. A
+// token that the lookahead set of state allows, but that none of these states
+// can shift, is only allowed after a reduction made for one of the other
+// places the same state is reached from, so it isn't allowed here.
+//
+// Hidden rules aren't in the tree, so the states after the hidden rules that
+// can start at one of the nodes, a sibling before it, or right after last are
+// included too, which can only let more tokens through. The result is nil if
+// the reductions can't be followed, as in an ERROR node, whose nodes the
+// parser didn't reduce as the grammar would, so that no token is left out.
+func reducedStates(language *tree_sitter.Language, state uint16, last *tree_sitter.Node) []uint16 {
+	for node := last; node != nil; node = node.Parent() {
+		if node.IsError() {
+			return nil
+		}
+	}
+	hidden := func(symbol uint16) bool { return !language.NodeKindIsVisible(symbol) }
+	states := []uint16{state}
+	if last != nil {
+		// Tokens that aren't in the tree, such as the text of a string,
+		// can follow last and start a hidden rule.
+		after, _ := gotos(language, stateAfter(language, last), hidden)
+		states = append(states, after...)
+	}
+	for node := last; node != nil; node = node.Parent() {
+		after, ok := gotos(language, stateBefore(language, node), func(symbol uint16) bool {
+			return symbol == node.GrammarId() || hidden(symbol)
+		})
+		if !ok {
+			return nil
+		}
+		states = append(states, after...)
+		for sibling := node.PrevSibling(); sibling != nil; sibling = sibling.PrevSibling() {
+			if sibling.IsExtra() {
+				continue
+			}
+			if after, ok = gotos(language, stateBefore(language, sibling), hidden); !ok {
+				return nil
+			}
+			states = append(states, after...)
+		}
+		if !isLastChild(node) {
+			break
+		}
+	}
+	return states
+}
+
+// gotos returns the states the parser goes to after reducing the rules that
+// can start in state before and that keep reports true for. It reports false
+// if before isn't a state, as when it couldn't be recovered.
+func gotos(language *tree_sitter.Language, before uint16, keep func(symbol uint16) bool) ([]uint16, bool) {
+	if before == 0 || before == noState {
+		return nil, false
+	}
+	iterator := language.LookaheadIterator(before)
+	if iterator == nil {
+		return nil, false
+	}
+	defer iterator.Close()
+	rules := language.IdForNodeKind("source_file", true)
+	var states []uint16
+	for _, symbol := range iterator.Iter() {
+		if symbol < rules || !keep(symbol) {
+			continue
+		}
+		if after := language.NextState(before, symbol); after != 0 {
+			states = append(states, after)
+		}
+	}
+	return states, true
+}
+
+// commentStart is the token that starts a comment.
+const commentStart = "# "
+
+// noState is the parse state of nodes that the parser built during error
+// recovery.
+const noState = 0xFFFF
+
+// stateAfter returns the parse state after the parser shifts node, or goes
+// to it after reducing its children.
+func stateAfter(language *tree_sitter.Language, node *tree_sitter.Node) uint16 {
+	return language.NextState(stateBefore(language, node), node.GrammarId())
+}
+
+// stateBefore returns the parse state in which the parser shifts node, or
+// reduces its children.
+//
+// A token only records the state it was lexed in. When the parser reduced the
+// nodes before it first, that isn't the state it was shifted in, which is
+// instead recovered from the nodes before it. Nodes built during error
+// recovery record no state at all, so their first token stands in for them,
+// or failing that the nodes before them.
+func stateBefore(language *tree_sitter.Language, node *tree_sitter.Node) uint16 {
+	if node.ChildCount() > 0 {
+		if node.ParseState() != noState {
+			return node.ParseState()
+		}
+		if state := stateBefore(language, node.Child(0)); state != 0 {
+			return state
+		}
+	} else if language.NextState(node.ParseState(), node.GrammarId()) != 0 {
+		return node.ParseState()
+	}
+
+	previous := node.PrevSibling()
+	for previous != nil && previous.IsExtra() {
+		previous = previous.PrevSibling()
+	}
+	if previous != nil {
+		return stateAfter(language, previous)
+	}
+	if parent := node.Parent(); parent != nil && parent.ParseState() != noState {
+		return parent.ParseState()
+	}
+	return 0
+}
+
+// tokensAround returns the last token that ends at or before pos and isn't
+// an extra, such as a comment, or nil if there is none, and the first leaf
+// after it of any kind, or nil if it's the last one. Tokens inside ERROR nodes
+// count, but missing tokens and empty ERROR nodes don't.
+func tokensAround(root *tree_sitter.Node, pos tree_sitter.Point) (last, next *tree_sitter.Node) {
+	cursor := root.Walk()
+	defer cursor.Close()
+
+	for cursor.GotoFirstChild() {
+		for {
+			node := cursor.Node()
+			if before(pos, node.StartPosition()) && next != nil {
+				return last, next
+			}
+			switch {
+			case node.IsExtra() && !node.IsError():
+				if last != nil && next == nil {
+					next = firstLeaf(node)
+				}
+			case node.ChildCount() > 0:
+				cursor.GotoFirstChild()
+				continue
+			case !before(pos, node.EndPosition()) && !node.IsMissing() && !node.IsError():
+				last, next = node, nil
+			case last != nil && next == nil && !node.IsMissing():
+				next = node
+			}
+			for !cursor.GotoNextSibling() {
+				if !cursor.GotoParent() || cursor.Node().Id() == root.Id() {
+					return last, next
+				}
+			}
+		}
+	}
+	return nil, nil
+}
+
+func isLastChild(node *tree_sitter.Node) bool {
+	parent := node.Parent()
+	if parent == nil {
+		return false
+	}
+	next := node.NextSibling()
+	for next != nil && next.IsExtra() && !next.IsError() {
+		next = next.NextSibling()
+	}
+	return next == nil
+}
+
+func firstLeaf(node *tree_sitter.Node) *tree_sitter.Node {
+	for node.ChildCount() > 0 {
+		node = node.Child(0)
+	}
+	return node
+}
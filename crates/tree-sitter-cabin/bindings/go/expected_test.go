@@ -0,0 +1,62 @@
+package tree_sitter_cabin_test
+
+import (
+	"slices"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestExpectedTokens(t *testing.T) {
+	for _, test := range []struct {
+		source      string
+		column      uint
+		want, never []string
+	}{
+		{"", 0, []string{"let", "action", "other_identifier"}, []string{";", "=>", "declaration"}},
+		{"let x = 1", 9, []string{";", "+", "."}, []string{"let", "number"}},
+		{"let x = 1;", 10, []string{"let", "number"}, []string{";"}},
+		{"let x = if c { } ", 17, []string{"otherwise", ";"}, []string{"let"}},
+		{"let x = match y { 1 ", 20, []string{"=>"}, []string{"let"}},
+		{"let y = a", 9, []string{"::", ".", "("}, []string{"number"}},
+		{"let y = action", 14, []string{"{", "("}, nil},
+		// Tokens allowed after the same state elsewhere are left out.
+		{"let x = 1;", 5, []string{"=", ":"}, []string{")", "]", "}", "in", "is", "=>", ","}},
+		{"let x = (a);", 10, []string{")", "+"}, []string{"]", "}", ";"}},
+		{"let s = \"a{b}\";", 12, []string{"}", "."}, []string{")", ";"}},
+		// The partially typed word after the cursor is ignored.
+		{"let y = a.prin;", 10, []string{"other_identifier"}, []string{";"}},
+	} {
+		tree := parse(t, test.source)
+		var names []string
+		for _, token := range tree_sitter_cabin.ExpectedTokens(tree, tree_sitter.NewPoint(0, test.column)) {
+			names = append(names, token.Name)
+		}
+		for _, name := range test.want {
+			if !slices.Contains(names, name) {
+				t.Errorf("ExpectedTokens(%q, %d) = %q, want it to contain %q", test.source, test.column, names, name)
+			}
+		}
+		for _, name := range test.never {
+			if slices.Contains(names, name) {
+				t.Errorf("ExpectedTokens(%q, %d) = %q, want it not to contain %q", test.source, test.column, names, name)
+			}
+		}
+	}
+}
+
+func TestExpectedTokensOrder(t *testing.T) {
+	tokens := tree_sitter_cabin.ExpectedTokens(parse(t, ""), tree_sitter.NewPoint(0, 0))
+	if len(tokens) == 0 {
+		t.Fatal("ExpectedTokens returned no tokens")
+	}
+	if tokens[0].Named || !tokens[len(tokens)-1].Named {
+		t.Errorf("ExpectedTokens = %v, want anonymous tokens before named ones", tokens)
+	}
+	for _, token := range tokens {
+		if token.Name == "comment" || token.Name == "# " {
+			t.Errorf("ExpectedTokens = %v, want no comment tokens", tokens)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package tree_sitter_cabin
+
+// Leaves returns the lengths of the leaves of the text of d, for tests of
+// how the rope keeps its leaves.
+func Leaves(d *Document) []int {
+	var lengths []int
+	var walk func(r *rope)
+	walk = func(r *rope) {
+		switch {
+		case r == nil:
+		case r.leaf != nil:
+			lengths = append(lengths, len(r.leaf))
+		default:
+			walk(r.left)
+			walk(r.right)
+		}
+	}
+	walk(d.text)
+	return lengths
+}
+
+// MaxLeaf is the most bytes a rope keeps in one leaf.
+const MaxLeaf = maxLeaf
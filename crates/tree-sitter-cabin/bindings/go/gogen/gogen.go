@@ -0,0 +1,495 @@
+// Package gogen generates Go types from the visible group and either
+// declarations of a Cabin library, so that Go services and Cabin scripts can
+// share data models.
+package gogen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Builtin maps the names of Cabin's built-in types to the Go types they are
+// generated as. Single-parameter generics map to a format string taking the
+// converted argument.
+var Builtin = map[string]string{
+	"Text":     "string",
+	"Number":   "float64",
+	"Boolean":  "bool",
+	"Any":      "any",
+	"List":     "[]%s",
+	"Optional": "*%s",
+}
+
+// A Type is a visible group or either declaration found in a Cabin source.
+type Type struct {
+	Name       string
+	Parameters []string
+	Either     bool
+	Fields     []Field
+}
+
+// A Field is a field of a group or a variant of an either. Type is empty for
+// variants that carry no data.
+type Field struct {
+	Name string
+	Type string
+}
+
+// An Error reports a declaration that can't be represented in Go.
+type Error struct {
+	File   string
+	Point  tree_sitter.Point
+	Reason string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Point.Row+1, e.Point.Column+1, e.Reason)
+}
+
+// Generate parses the given Cabin sources and returns a formatted Go file in
+// package pkg declaring a type for every visible group and either. Field
+// types may refer to the types declared in any of the files.
+func Generate(pkg string, files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parser, err := newParser()
+	if err != nil {
+		return nil, err
+	}
+	defer parser.Close()
+	found := make([][]typeDeclaration, len(names))
+	declared := map[string]bool{}
+	for i, name := range names {
+		tree := parser.Parse(files[name], nil)
+		defer tree.Close()
+		found[i] = typeDeclarations(tree.RootNode(), files[name])
+		for _, declaration := range found[i] {
+			declared[declaration.name] = true
+		}
+	}
+
+	var types []Type
+	for i, name := range names {
+		converted, err := convert(name, files[name], found[i], declared)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, converted...)
+	}
+	return Render(pkg, types)
+}
+
+// GenerateFS is like Generate, reading every .cabin file under root in fsys.
+func GenerateFS(pkg string, fsys fs.FS, root string) ([]byte, error) {
+	files := map[string][]byte{}
+	err := fs.WalkDir(fsys, root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(name) != ".cabin" {
+			return err
+		}
+		source, err := fs.ReadFile(fsys, name)
+		files[name] = source
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Generate(pkg, files)
+}
+
+// Declarations returns the visible group and either declarations at the top
+// level of source, with their field types already converted to Go. Field
+// types may refer to the types declared in source.
+func Declarations(file string, source []byte) ([]Type, error) {
+	parser, err := newParser()
+	if err != nil {
+		return nil, err
+	}
+	defer parser.Close()
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	found := typeDeclarations(tree.RootNode(), source)
+	declared := map[string]bool{}
+	for _, declaration := range found {
+		declared[declaration.name] = true
+	}
+	return convert(file, source, found, declared)
+}
+
+func newParser() (*tree_sitter.Parser, error) {
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		parser.Close()
+		return nil, err
+	}
+	return parser, nil
+}
+
+// A typeDeclaration is a visible group or either declaration.
+type typeDeclaration struct {
+	name    string
+	literal *tree_sitter.Node
+}
+
+// typeDeclarations returns the visible group and either declarations at the
+// top level of root.
+func typeDeclarations(root *tree_sitter.Node, source []byte) []typeDeclaration {
+	var found []typeDeclaration
+	for i := uint(0); i < root.NamedChildCount(); i++ {
+		declaration := root.NamedChild(i).NamedChild(0)
+		if declaration == nil || declaration.Kind() != "declaration" || declaration.ChildByFieldName("visible") == nil {
+			continue
+		}
+		literal := tree_sitter_cabin.Unwrap(declaration.ChildByFieldName("value"))
+		if literal == nil || (literal.Kind() != "group" && literal.Kind() != "either") {
+			continue
+		}
+		found = append(found, typeDeclaration{name: declaration.ChildByFieldName("name").Utf8Text(source), literal: literal})
+	}
+	return found
+}
+
+// convert converts the declarations found in a file to Types. declared holds
+// the names of the types that field types may refer to.
+func convert(file string, source []byte, found []typeDeclaration, declared map[string]bool) ([]Type, error) {
+	c := converter{file: file, source: source, declared: declared}
+	var types []Type
+	for _, declaration := range found {
+		if declaration.literal.HasError() {
+			return nil, c.errorf(declaration.literal, "syntax error in declaration")
+		}
+		typ, err := c.declaration(declaration.name, declaration.literal)
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, typ)
+	}
+	return types, nil
+}
+
+type converter struct {
+	file       string
+	source     []byte
+	declared   map[string]bool
+	parameters map[string]bool
+}
+
+func (c *converter) errorf(node *tree_sitter.Node, format string, args ...any) error {
+	return &Error{File: c.file, Point: node.StartPosition(), Reason: fmt.Sprintf(format, args...)}
+}
+
+func (c *converter) declaration(name string, literal *tree_sitter.Node) (Type, error) {
+	typ := Type{Name: name, Either: literal.Kind() == "either"}
+	c.parameters = map[string]bool{}
+
+	member, children := "group_field", "fields"
+	if typ.Either {
+		member, children = "either_variant", "variants"
+	}
+
+	cursor := literal.Walk()
+	defer cursor.Close()
+	for _, parameter := range literal.ChildrenByFieldName("compile_time_parameters", cursor) {
+		if parameter.Kind() == "group_parameter" {
+			name := parameter.ChildByFieldName("name").Utf8Text(c.source)
+			typ.Parameters = append(typ.Parameters, name)
+			c.parameters[name] = true
+		}
+	}
+	for _, child := range literal.ChildrenByFieldName(children, cursor) {
+		if child.Kind() != member {
+			continue
+		}
+		field := Field{Name: child.ChildByFieldName("name").Utf8Text(c.source)}
+		if annotation := child.ChildByFieldName("type"); annotation != nil {
+			if isAction(annotation) {
+				continue
+			}
+			converted, err := c.goType(annotation)
+			if err != nil {
+				return Type{}, err
+			}
+			field.Type = converted
+		} else if value := tree_sitter_cabin.Unwrap(child.ChildByFieldName("value")); value != nil {
+			switch value.Kind() {
+			case "function":
+				continue
+			case "number":
+				field.Type = "float64"
+			case "string", "raw_string":
+				field.Type = "string"
+			default:
+				return Type{}, c.errorf(&child, "can't infer the type of field %q", field.Name)
+			}
+		} else if !typ.Either {
+			field.Type = "any"
+		}
+		typ.Fields = append(typ.Fields, field)
+	}
+	if typ.Either && len(typ.Fields) == 0 {
+		return Type{}, c.errorf(literal, "either %s has no variants", name)
+	}
+	return typ, nil
+}
+
+// goType converts a Cabin type or expression node to Go type syntax.
+func (c *converter) goType(node *tree_sitter.Node) (string, error) {
+	for node.Kind() == "type" || node.Kind() == "expression" || node.Kind() == "literal" || node.Kind() == "postfix" {
+		if node.NamedChildCount() != 1 {
+			return "", c.errorf(node, "unsupported type %q", node.Utf8Text(c.source))
+		}
+		node = node.NamedChild(0)
+	}
+
+	switch node.Kind() {
+	case "identifier":
+		name := node.Utf8Text(c.source)
+		if goName, ok := Builtin[name]; ok && !strings.Contains(goName, "%s") {
+			return goName, nil
+		}
+		if c.parameters[name] || c.declared[name] {
+			return name, nil
+		}
+		return "", c.errorf(node, "unknown type %q", name)
+
+	case "function_call":
+		callee := tree_sitter_cabin.Unwrap(node.ChildByFieldName("callee"))
+		cursor := node.Walk()
+		defer cursor.Close()
+		var arguments []string
+		for _, argument := range node.ChildrenByFieldName("compile_time_arguments", cursor) {
+			if argument.Kind() != "type" {
+				continue
+			}
+			converted, err := c.goType(&argument)
+			if err != nil {
+				return "", err
+			}
+			arguments = append(arguments, converted)
+		}
+		if callee == nil || callee.Kind() != "identifier" || node.ChildByFieldName("arguments") != nil {
+			return "", c.errorf(node, "unsupported type %q", node.Utf8Text(c.source))
+		}
+		name := callee.Utf8Text(c.source)
+		if goName, ok := Builtin[name]; ok && strings.Contains(goName, "%s") {
+			if len(arguments) != 1 {
+				return "", c.errorf(node, "%s takes exactly one type argument", name)
+			}
+			return fmt.Sprintf(goName, arguments[0]), nil
+		}
+		if !c.declared[name] {
+			return "", c.errorf(node, "unknown type %q", name)
+		}
+		return name + "[" + strings.Join(arguments, ", ") + "]", nil
+	}
+	return "", c.errorf(node, "unsupported type %q", node.Utf8Text(c.source))
+}
+
+// isAction reports whether a type annotation is an action signature. Fields
+// with action types are methods and have no data to generate.
+func isAction(annotation *tree_sitter.Node) bool {
+	node := annotation
+	if node.NamedChildCount() == 1 {
+		node = node.NamedChild(0)
+	}
+	node = tree_sitter_cabin.Unwrap(node)
+	for node != nil && node.Kind() == "function_call" {
+		node = tree_sitter_cabin.Unwrap(node.ChildByFieldName("callee"))
+	}
+	return node != nil && node.Kind() == "function"
+}
+
+// exportedName converts a Cabin snake_case name to a Go exported name. A name
+// made only of underscores has none, and converts to "".
+func exportedName(name string) string {
+	var builder strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		builder.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return builder.String()
+}
+
+// Render returns a formatted Go file in package pkg declaring the given types.
+func Render(pkg string, types []Type) ([]byte, error) {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gogen from Cabin declarations. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+
+	needsFmt := false
+	for _, typ := range types {
+		if typ.Either {
+			needsFmt = true
+		}
+	}
+	if needsFmt {
+		out.WriteString("import (\n\"encoding/json\"\n\"fmt\"\n)\n\n")
+	}
+
+	for _, typ := range types {
+		var err error
+		switch {
+		case !typ.Either:
+			err = renderGroup(&out, typ)
+		case hasData(typ):
+			err = renderEither(&out, typ)
+		default:
+			err = renderEnum(&out, typ)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+func hasData(typ Type) bool {
+	for _, field := range typ.Fields {
+		if field.Type != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func typeParameters(typ Type) (declared, applied string) {
+	if len(typ.Parameters) == 0 {
+		return "", ""
+	}
+	return "[" + strings.Join(typ.Parameters, ", ") + " any]", "[" + strings.Join(typ.Parameters, ", ") + "]"
+}
+
+func renderGroup(out *bytes.Buffer, typ Type) error {
+	declared, _ := typeParameters(typ)
+	fmt.Fprintf(out, "// %s is generated from the Cabin group %s.\ntype %s%s struct {\n", typ.Name, typ.Name, typ.Name, declared)
+	seen := map[string]bool{}
+	for _, field := range typ.Fields {
+		name := exportedName(field.Name)
+		if name == "" {
+			return fmt.Errorf("%s: field %q has no Go name", typ.Name, field.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s: fields collide as %s", typ.Name, name)
+		}
+		seen[name] = true
+		fmt.Fprintf(out, "%s %s `json:%q`\n", name, field.Type, field.Name)
+	}
+	out.WriteString("}\n\n")
+	return nil
+}
+
+// renderEnum renders an either without data as a string type whose values
+// marshal to the variant names.
+func renderEnum(out *bytes.Buffer, typ Type) error {
+	if len(typ.Parameters) > 0 {
+		return fmt.Errorf("%s: either without data can't have compile-time parameters", typ.Name)
+	}
+	if len(typ.Fields) == 0 {
+		return fmt.Errorf("%s: either has no variants", typ.Name)
+	}
+	fmt.Fprintf(out, "// %s is generated from the Cabin either %s.\ntype %s string\n\nconst (\n", typ.Name, typ.Name, typ.Name)
+	seen := map[string]bool{}
+	for _, field := range typ.Fields {
+		name := exportedName(field.Name)
+		if name == "" {
+			return fmt.Errorf("%s: variant %q has no Go name", typ.Name, field.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s: variants collide as %s%s", typ.Name, typ.Name, name)
+		}
+		seen[name] = true
+		fmt.Fprintf(out, "%s%s %s = %q\n", typ.Name, exportedName(field.Name), typ.Name, field.Name)
+	}
+	out.WriteString(")\n\n")
+
+	fmt.Fprintf(out, "// UnmarshalJSON accepts only the variant names of %s.\nfunc (e *%s) UnmarshalJSON(data []byte) error {\n", typ.Name, typ.Name)
+	out.WriteString("var name string\nif err := json.Unmarshal(data, &name); err != nil {\nreturn err\n}\nswitch name {\ncase ")
+	for i, field := range typ.Fields {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		fmt.Fprintf(out, "%q", field.Name)
+	}
+	fmt.Fprintf(out, ":\n*e = %s(name)\nreturn nil\n}\nreturn fmt.Errorf(\"unknown %s variant %%q\", name)\n}\n\n", typ.Name, typ.Name)
+	return nil
+}
+
+// renderEither renders an either with data as a struct holding the variant
+// name and a pointer per data-carrying variant. Variants without data marshal
+// to their name; variants with data marshal to an object with a single key.
+func renderEither(out *bytes.Buffer, typ Type) error {
+	declared, applied := typeParameters(typ)
+	fmt.Fprintf(out, "// %s is generated from the Cabin either %s. Variant holds the name of the\n// active variant, and the field of the same name holds its data, if any.\n", typ.Name, typ.Name)
+	fmt.Fprintf(out, "type %s%s struct {\nVariant string\n", typ.Name, declared)
+	seen := map[string]bool{}
+	for _, field := range typ.Fields {
+		name := exportedName(field.Name)
+		if name == "" {
+			return fmt.Errorf("%s: variant %q has no Go name", typ.Name, field.Name)
+		}
+		if name == "Variant" {
+			return fmt.Errorf("%s: variant %q collides with the Variant field", typ.Name, field.Name)
+		}
+		if seen[name] {
+			return fmt.Errorf("%s: variants collide as %s", typ.Name, name)
+		}
+		seen[name] = true
+		if field.Type != "" {
+			fmt.Fprintf(out, "%s *%s\n", name, field.Type)
+		}
+	}
+	out.WriteString("}\n\n")
+
+	fmt.Fprintf(out, "// MarshalJSON encodes the active variant of %s.\nfunc (e %s%s) MarshalJSON() ([]byte, error) {\nswitch e.Variant {\n", typ.Name, typ.Name, applied)
+	for _, field := range typ.Fields {
+		if field.Type == "" {
+			fmt.Fprintf(out, "case %q:\nreturn json.Marshal(e.Variant)\n", field.Name)
+		} else {
+			fmt.Fprintf(out, "case %q:\nreturn json.Marshal(map[string]any{e.Variant: e.%s})\n", field.Name, exportedName(field.Name))
+		}
+	}
+	fmt.Fprintf(out, "}\nreturn nil, fmt.Errorf(\"unknown %s variant %%q\", e.Variant)\n}\n\n", typ.Name)
+
+	fmt.Fprintf(out, "// UnmarshalJSON decodes a variant of %s encoded by MarshalJSON.\nfunc (e *%s%s) UnmarshalJSON(data []byte) error {\n", typ.Name, typ.Name, applied)
+	fmt.Fprintf(out, "*e = %s%s{}\n", typ.Name, applied)
+	var units []string
+	for _, field := range typ.Fields {
+		if field.Type == "" {
+			units = append(units, fmt.Sprintf("%q", field.Name))
+		}
+	}
+	if len(units) > 0 {
+		out.WriteString("if err := json.Unmarshal(data, &e.Variant); err == nil {\nswitch e.Variant {\n")
+		fmt.Fprintf(out, "case %s:\nreturn nil\n}\nreturn fmt.Errorf(\"unknown %s variant %%q\", e.Variant)\n}\n", strings.Join(units, ", "), typ.Name)
+	}
+	out.WriteString("var object map[string]json.RawMessage\nif err := json.Unmarshal(data, &object); err != nil {\nreturn err\n}\n")
+	fmt.Fprintf(out, "if len(object) != 1 {\nreturn fmt.Errorf(\"decoding %s: expected exactly one variant, found %%d\", len(object))\n}\n", typ.Name)
+	out.WriteString("for name, value := range object {\ne.Variant = name\nswitch name {\n")
+	for _, field := range typ.Fields {
+		if field.Type == "" {
+			continue
+		}
+		name := exportedName(field.Name)
+		fmt.Fprintf(out, "case %q:\ne.%s = new(%s)\nreturn json.Unmarshal(value, e.%s)\n", field.Name, name, field.Type, name)
+	}
+	fmt.Fprintf(out, "}\n}\nreturn fmt.Errorf(\"unknown %s variant %%q\", e.Variant)\n}\n\n", typ.Name)
+	return nil
+}
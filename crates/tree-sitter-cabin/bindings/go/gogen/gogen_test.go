@@ -0,0 +1,122 @@
+package gogen_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/gogen"
+)
+
+const library = `
+let visible Person = group<Id: Any> {
+	visible name: Text,
+	id: Id,
+	nicknames: List<Text>,
+	manager: Optional<Person<Id>>,
+	age = 3,
+	greet = action {},
+};
+
+let visible Ordering = either { less, greater, equal };
+
+let visible Shape = either {
+	circle: Number,
+	square: Number,
+	empty,
+};
+
+let Hidden = group { value: Text };
+`
+
+func TestGenerate(t *testing.T) {
+	source, err := gogen.Generate("models", map[string][]byte{"library.cabin": []byte(library)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := strings.Join(strings.Fields(string(source)), " ")
+
+	for _, want := range []string{
+		"type Person[Id any] struct",
+		"Nicknames []string `json:\"nicknames\"`",
+		"Manager *Person[Id] `json:\"manager\"`",
+		"Age float64 `json:\"age\"`",
+		"type Ordering string",
+		`OrderingGreater Ordering = "greater"`,
+		"Circle *float64",
+	} {
+		if !strings.Contains(generated, want) {
+			t.Errorf("generated code doesn't contain %q:\n%s", want, source)
+		}
+	}
+	for _, unwanted := range []string{"Greet", "Hidden"} {
+		if strings.Contains(generated, unwanted) {
+			t.Errorf("generated code contains %q:\n%s", unwanted, source)
+		}
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "models.go", source, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := config.Check("models", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("generated code doesn't type-check: %v\n%s", err, source)
+	}
+}
+
+func TestGenerateUnsupportedType(t *testing.T) {
+	_, err := gogen.Generate("models", map[string][]byte{
+		"library.cabin": []byte("let visible Bad = group { value: lowercase };"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "library.cabin:1:34") {
+		t.Errorf("expected a positioned error, got %v", err)
+	}
+}
+
+func TestGenerateReferences(t *testing.T) {
+	source, err := gogen.Generate("models", map[string][]byte{
+		"a.cabin": []byte("let visible point = group { x: Number, callback: action };"),
+		"b.cabin": []byte("let visible Line = group { from: point, to: point };"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := strings.Join(strings.Fields(string(source)), " ")
+	for _, want := range []string{"type point struct", "From point `json:\"from\"`"} {
+		if !strings.Contains(generated, want) {
+			t.Errorf("generated code doesn't contain %q:\n%s", want, source)
+		}
+	}
+	if strings.Contains(generated, "Callback") {
+		t.Errorf("generated a field for an action:\n%s", source)
+	}
+
+	// Each file on its own only knows its own types.
+	if _, err := gogen.Declarations("b.cabin", []byte("let visible Line = group { from: point };")); err == nil || !strings.Contains(err.Error(), `unknown type "point"`) {
+		t.Errorf("expected an unknown type error, got %v", err)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		"let visible Bad = group { value: Object };":       `library.cabin:1:34: unknown type "Object"`,
+		"let visible Bad = group { value: Object<Text> };": `library.cabin:1:34: unknown type "Object"`,
+		"let visible Bad = either {};":                     "library.cabin:1:19: either Bad has no variants",
+		"let visible Bad = either { a_b, aB };":            "Bad: variants collide as BadAB",
+		"let visible Bad = either { a_b: Text, aB };":      "Bad: variants collide as AB",
+		"let visible Point = group { _: Number };":         `Point: field "_" has no Go name`,
+		"let visible Bad = either { __ };":                 `Bad: variant "__" has no Go name`,
+		"let visible Bad = either { _: Text };":            `Bad: variant "_" has no Go name`,
+	} {
+		_, err := gogen.Generate("models", map[string][]byte{"library.cabin": []byte(source)})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: got error %v, want %s", source, err, want)
+		}
+	}
+}
@@ -0,0 +1,500 @@
+package grammar
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// GenerateOptions configures Generate.
+type GenerateOptions struct {
+	// Start is the rule to generate, or the first rule of the grammar if
+	// it's empty.
+	Start string
+	// MaxDepth is how deeply rules may nest, and MaxSize roughly how many
+	// tokens the program may have, before the generator finishes it the
+	// shortest way it can. Zero means DefaultMaxDepth and DefaultMaxSize.
+	MaxDepth, MaxSize int
+	// Scope, if not nil, makes the generated identifiers refer to names
+	// declared before them.
+	Scope *Scope
+	// Valid, if not nil, reports whether a program is valid, typically by
+	// parsing it. Generate returns only programs it accepts.
+	Valid func(program string) bool
+}
+
+// The limits of GenerateOptions that zero stands for. Without a limit, the
+// recursive rules of a grammar can nest until the stack overflows.
+const (
+	DefaultMaxDepth = 20
+	DefaultMaxSize  = 200
+)
+
+// maxAttempts is how many programs Generate tries before it gives up on
+// finding a valid one, and maxSamples how many matches of a pattern it tries
+// before it gives up on finding one that isn't a keyword.
+const (
+	maxAttempts = 100
+	maxSamples  = 100
+)
+
+// A Scope tells Generate which identifiers declare names and which refer to
+// them.
+type Scope struct {
+	// Identifier is the rule that names are.
+	Identifier string
+	// Declarations are the fields whose identifier declares a name, as
+	// rule.field. The name is in scope after the rule.
+	Declarations []string
+	// References are the rules in which an identifier refers to a name.
+	References []string
+	// Blocks are the rules that the names declared in them are local to.
+	Blocks []string
+	// Builtins are the names in scope from the start.
+	Builtins []string
+}
+
+// CabinScope is the Scope of Cabin programs.
+var CabinScope = &Scope{
+	Identifier:   "identifier",
+	Declarations: []string{"declaration.name", "parameter.name", "group_parameter.name", "foreach.binding"},
+	References:   []string{"literal", "object_constructor"},
+	Blocks:       []string{"block", "function", "group", "either"},
+	Builtins:     []string{"system", "Text", "Number", "Boolean", "Any", "true", "false"},
+}
+
+// Generate returns a random program derived from the rules of the grammar.
+//
+// The program is tokens of the grammar separated by spaces, so it parses as
+// long as the parser accepts every string the rules derive. Precedences and
+// conflicts make the parser read some of them differently, which Valid can
+// filter out.
+//
+// If the start rule is a repetition, such as the statements of a source
+// file, the program has at least one repeat, so that it isn't empty.
+func (g *Grammar) Generate(r *rand.Rand, options GenerateOptions) (string, error) {
+	start := options.Start
+	if start == "" && len(g.Rules) > 0 {
+		start = g.Rules[0].Name
+	}
+	if options.MaxDepth == 0 {
+		options.MaxDepth = DefaultMaxDepth
+	}
+	if options.MaxSize == 0 {
+		options.MaxSize = DefaultMaxSize
+	}
+	generator := &generator{
+		rand:     r,
+		options:  options,
+		rules:    map[string]Rule{},
+		costs:    map[string]float64{},
+		patterns: map[string]*syntax.Regexp{},
+		keywords: map[string]bool{},
+	}
+	for _, definition := range g.Rules {
+		generator.rules[definition.Name] = definition.Rule
+		generator.keywords = keywords(definition.Rule, generator.keywords)
+	}
+	if generator.rules[start] == nil {
+		return "", fmt.Errorf("no rule %s", start)
+	}
+	if repeat, ok := generator.rules[start].(Repeat); ok {
+		generator.rules[start] = Repeat1(repeat)
+	}
+	generator.computeCosts()
+
+	for range maxAttempts {
+		generator.tokens, generator.finishing = nil, false
+		if options.Scope != nil {
+			generator.scopes = [][]string{options.Scope.Builtins}
+		}
+		if err := generator.symbol(start, ""); err != nil {
+			return "", err
+		}
+		program := generator.program()
+		if options.Valid == nil || options.Valid(program) {
+			return program, nil
+		}
+	}
+	return "", fmt.Errorf("no valid %s in %d attempts", start, maxAttempts)
+}
+
+type generator struct {
+	rand     *rand.Rand
+	options  GenerateOptions
+	rules    map[string]Rule
+	costs    map[string]float64
+	patterns map[string]*syntax.Regexp
+	keywords map[string]bool
+
+	tokens []token
+	depth  int
+	// finishing is set once the program reaches MaxSize.
+	finishing bool
+
+	scopes [][]string
+	// declaring collects the names declared in the rule being generated,
+	// when it's in a declaring field.
+	declaring *[]string
+}
+
+type token struct {
+	text      string
+	immediate bool
+}
+
+// program joins the tokens with spaces. A space isn't written where it would
+// make a token into another, like "#" followed by a space into "# ".
+func (g *generator) program() string {
+	var program strings.Builder
+	for i, token := range g.tokens {
+		if i > 0 && !token.immediate {
+			previous := g.tokens[i-1].text
+			switch {
+			case strings.HasSuffix(previous, " "), strings.HasPrefix(token.text, " "):
+			case g.keywords[previous+" "], g.keywords[" "+token.text]:
+			default:
+				program.WriteByte(' ')
+			}
+		}
+		program.WriteString(token.text)
+	}
+	return program.String()
+}
+
+// keywords adds the strings of rule to words.
+func keywords(rule Rule, words map[string]bool) map[string]bool {
+	walkRule(rule, func(rule Rule) {
+		if s, ok := rule.(String); ok {
+			words[s.Value] = true
+		}
+	})
+	return words
+}
+
+// walkRule calls f for rule and every rule in it.
+func walkRule(rule Rule, f func(Rule)) {
+	f(rule)
+	switch rule := rule.(type) {
+	case Seq:
+		for _, member := range rule.Members {
+			walkRule(member, f)
+		}
+	case Choice:
+		for _, member := range rule.Members {
+			walkRule(member, f)
+		}
+	default:
+		if content := contentOf(rule); content != nil {
+			walkRule(content, f)
+		}
+	}
+}
+
+// contentOf returns the content of a rule that wraps a single rule.
+func contentOf(rule Rule) Rule {
+	switch rule := rule.(type) {
+	case Repeat:
+		return rule.Content
+	case Repeat1:
+		return rule.Content
+	case Field:
+		return rule.Content
+	case Alias:
+		return rule.Content
+	case Token:
+		return rule.Content
+	case Prec:
+		return rule.Content
+	case Reserved:
+		return rule.Content
+	}
+	return nil
+}
+
+// computeCosts computes the fewest tokens each rule can be generated with.
+func (g *generator) computeCosts() {
+	for name := range g.rules {
+		g.costs[name] = math.Inf(1)
+	}
+	for changed := true; changed; {
+		changed = false
+		for name, rule := range g.rules {
+			if cost := g.cost(rule); cost < g.costs[name] {
+				g.costs[name], changed = cost, true
+			}
+		}
+	}
+}
+
+func (g *generator) cost(rule Rule) float64 {
+	switch rule := rule.(type) {
+	case Blank, Repeat:
+		return 0
+	case String, Pattern:
+		return 1
+	case Symbol:
+		if cost, ok := g.costs[rule.Name]; ok {
+			return cost
+		}
+		return math.Inf(1)
+	case Seq:
+		total := 0.0
+		for _, member := range rule.Members {
+			total += g.cost(member)
+		}
+		return total
+	case Choice:
+		least := math.Inf(1)
+		for _, member := range rule.Members {
+			least = min(least, g.cost(member))
+		}
+		return least
+	case Token:
+		return 1
+	}
+	return g.cost(contentOf(rule))
+}
+
+// limited reports whether the generator has to finish the rule it's in the
+// shortest way it can.
+func (g *generator) limited() bool {
+	return g.finishing || g.depth > g.options.MaxDepth
+}
+
+// symbol generates the rule called name, used directly in the rule parent.
+func (g *generator) symbol(name, parent string) error {
+	rule, ok := g.rules[name]
+	if !ok {
+		return fmt.Errorf("no rule %s", name)
+	}
+	scope := g.options.Scope
+	if scope != nil && name == scope.Identifier {
+		switch {
+		case g.declaring != nil:
+			text, err := g.name()
+			if err != nil {
+				return err
+			}
+			*g.declaring = append(*g.declaring, text)
+			g.tokens = append(g.tokens, token{text: text})
+			return nil
+		case slices.Contains(scope.References, parent):
+			var visible []string
+			for _, names := range g.scopes {
+				visible = append(visible, names...)
+			}
+			if len(visible) == 0 {
+				return fmt.Errorf("%s refers to a name before any is declared and there are no builtins", parent)
+			}
+			g.tokens = append(g.tokens, token{text: visible[g.rand.Intn(len(visible))]})
+			return nil
+		}
+	}
+
+	block := scope != nil && slices.Contains(scope.Blocks, name)
+	if block {
+		g.scopes = append(g.scopes, nil)
+	}
+	var declared []string
+	outer := g.declaring
+	g.declaring = nil
+	g.depth++
+	err := g.generate(rule, name, &declared)
+	g.depth--
+	g.declaring = outer
+	if block {
+		g.scopes = g.scopes[:len(g.scopes)-1]
+	}
+	if scope != nil && len(declared) > 0 {
+		g.scopes[len(g.scopes)-1] = append(g.scopes[len(g.scopes)-1], declared...)
+	}
+	return err
+}
+
+// name generates a name for a declaration from the identifier rule.
+func (g *generator) name() (string, error) {
+	start := len(g.tokens)
+	outer := g.declaring
+	g.declaring = nil
+	defer func() { g.declaring = outer }()
+	if err := g.generate(g.rules[g.options.Scope.Identifier], g.options.Scope.Identifier, nil); err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, token := range g.tokens[start:] {
+		text.WriteString(token.text)
+	}
+	g.tokens = g.tokens[:start]
+	return text.String(), nil
+}
+
+// generate generates rule, which is in the rule called parent. The names
+// declared in it are added to declared.
+func (g *generator) generate(rule Rule, parent string, declared *[]string) error {
+	switch rule := rule.(type) {
+	case Blank:
+	case String:
+		g.tokens = append(g.tokens, token{text: rule.Value})
+	case Pattern:
+		text, err := g.sample(rule)
+		if err != nil {
+			return err
+		}
+		g.tokens = append(g.tokens, token{text: text})
+	case Symbol:
+		return g.symbol(rule.Name, parent)
+	case Seq:
+		for _, member := range rule.Members {
+			if err := g.generate(member, parent, declared); err != nil {
+				return err
+			}
+		}
+	case Choice:
+		return g.generate(g.choose(rule.Members), parent, declared)
+	case Repeat, Repeat1:
+		n := 0
+		if _, ok := rule.(Repeat1); ok {
+			n = 1
+		}
+		if !g.limited() {
+			for n < 8 && g.rand.Intn(2) == 0 {
+				n++
+			}
+		}
+		for range n {
+			if err := g.generate(contentOf(rule), parent, declared); err != nil {
+				return err
+			}
+		}
+	case Field:
+		scope := g.options.Scope
+		if scope != nil && declared != nil && slices.Contains(scope.Declarations, parent+"."+rule.Name) {
+			outer := g.declaring
+			g.declaring = declared
+			defer func() { g.declaring = outer }()
+		}
+		return g.generate(rule.Content, parent, declared)
+	case Token:
+		// The parts of a token are written without spaces between them.
+		start := len(g.tokens)
+		if err := g.generate(rule.Content, parent, declared); err != nil {
+			return err
+		}
+		for i := start + 1; i < len(g.tokens); i++ {
+			g.tokens[i].immediate = true
+		}
+		if rule.Immediate && start < len(g.tokens) {
+			g.tokens[start].immediate = true
+		}
+	default:
+		return g.generate(contentOf(rule), parent, declared)
+	}
+	if len(g.tokens) >= g.options.MaxSize {
+		g.finishing = true
+	}
+	return nil
+}
+
+// choose picks a member of a choice: at random, or the cheapest when a limit
+// has been reached.
+func (g *generator) choose(members []Rule) Rule {
+	if !g.limited() {
+		return members[g.rand.Intn(len(members))]
+	}
+	var cheapest []Rule
+	least := math.Inf(1)
+	for _, member := range members {
+		switch cost := g.cost(member); {
+		case cost < least:
+			cheapest, least = []Rule{member}, cost
+		case cost == least:
+			cheapest = append(cheapest, member)
+		}
+	}
+	return cheapest[g.rand.Intn(len(cheapest))]
+}
+
+// unicodeEscape matches the \uXXXX escapes of JavaScript regular expressions,
+// which Go spells \x{XXXX}.
+var unicodeEscape = regexp.MustCompile(`\\u([0-9a-fA-F]{4})`)
+
+// sample returns a random match of a pattern that isn't a keyword.
+func (g *generator) sample(pattern Pattern) (string, error) {
+	re, ok := g.patterns[pattern.Value]
+	if !ok {
+		var err error
+		re, err = syntax.Parse(unicodeEscape.ReplaceAllString(pattern.Value, `\x{$1}`), syntax.Perl)
+		if err != nil {
+			return "", fmt.Errorf("pattern /%s/: %w", pattern.Value, err)
+		}
+		g.patterns[pattern.Value] = re
+	}
+	for range maxSamples {
+		var text strings.Builder
+		g.sampleRegexp(re, &text)
+		if !g.keywords[text.String()] {
+			return text.String(), nil
+		}
+	}
+	return "", fmt.Errorf("pattern /%s/ matched only keywords in %d samples", pattern.Value, maxSamples)
+}
+
+func (g *generator) sampleRegexp(re *syntax.Regexp, text *strings.Builder) {
+	repeat := func(least, most int) {
+		n := least + g.rand.Intn(most-least+1)
+		for range n {
+			g.sampleRegexp(re.Sub[0], text)
+		}
+	}
+	switch re.Op {
+	case syntax.OpLiteral:
+		text.WriteString(string(re.Rune))
+	case syntax.OpCharClass:
+		text.WriteRune(g.sampleClass(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		text.WriteByte(byte('a' + g.rand.Intn(26)))
+	case syntax.OpCapture:
+		g.sampleRegexp(re.Sub[0], text)
+	case syntax.OpStar:
+		repeat(0, 3)
+	case syntax.OpPlus:
+		repeat(1, 4)
+	case syntax.OpQuest:
+		repeat(0, 1)
+	case syntax.OpRepeat:
+		most := re.Max
+		if most < 0 {
+			most = re.Min + 3
+		}
+		repeat(re.Min, min(most, re.Min+3))
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.sampleRegexp(sub, text)
+		}
+	case syntax.OpAlternate:
+		g.sampleRegexp(re.Sub[g.rand.Intn(len(re.Sub))], text)
+	}
+}
+
+// sampleClass picks a rune from the ranges of a character class, preferring
+// printable ASCII so that programs stay readable.
+func (g *generator) sampleClass(ranges []rune) rune {
+	var printable []rune
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := max(ranges[i], '!'); r <= min(ranges[i+1], '~'); r++ {
+			if unicode.IsPrint(r) {
+				printable = append(printable, r)
+			}
+		}
+	}
+	if len(printable) > 0 {
+		return printable[g.rand.Intn(len(printable))]
+	}
+	return ranges[0]
+}
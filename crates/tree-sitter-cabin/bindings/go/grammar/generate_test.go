@@ -0,0 +1,194 @@
+package grammar_test
+
+import (
+	"math/rand"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/grammar"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestGenerate(t *testing.T) {
+	g := load(t)
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	valid := func(program string) bool {
+		tree := parser.Parse([]byte(program), nil)
+		defer tree.Close()
+		return !tree.RootNode().HasError()
+	}
+
+	for _, scope := range []*grammar.Scope{nil, grammar.CabinScope} {
+		for seed := range 200 {
+			options := grammar.GenerateOptions{MaxDepth: 20, MaxSize: 200, Scope: scope, Valid: valid}
+			program, err := g.Generate(rand.New(rand.NewSource(int64(seed))), options)
+			if err != nil {
+				t.Fatalf("seed %d: %v", seed, err)
+			}
+			if !valid(program) {
+				t.Fatalf("seed %d: generated a program that doesn't parse: %s", seed, program)
+			}
+			again, _ := g.Generate(rand.New(rand.NewSource(int64(seed))), options)
+			if again != program {
+				t.Fatalf("seed %d: generated %q, then %q", seed, program, again)
+			}
+		}
+	}
+}
+
+func TestGenerateLimits(t *testing.T) {
+	g := load(t)
+	for seed := range 100 {
+		program, err := g.Generate(rand.New(rand.NewSource(int64(seed))), grammar.GenerateOptions{Start: "expression", MaxDepth: 4, MaxSize: 20})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Finishing a program after the limit adds a few tokens at each
+		// level it's in.
+		if tokens := len(strings.Fields(program)); tokens > 100 {
+			t.Errorf("seed %d: generated %d tokens, over the limit of 20: %s", seed, tokens, program)
+		}
+	}
+	// Zero limits stand for the defaults rather than no limit.
+	for seed := range 20 {
+		program, err := g.Generate(rand.New(rand.NewSource(int64(seed))), grammar.GenerateOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if program == "" {
+			t.Errorf("seed %d: generated an empty program", seed)
+		}
+		if tokens := len(strings.Fields(program)); tokens > 10*grammar.DefaultMaxSize {
+			t.Errorf("seed %d: generated %d tokens with the default limits", seed, tokens)
+		}
+	}
+	if _, err := g.Generate(rand.New(rand.NewSource(1)), grammar.GenerateOptions{Start: "missing"}); err == nil {
+		t.Error("generated a rule that doesn't exist")
+	}
+	never := func(string) bool { return false }
+	if _, err := g.Generate(rand.New(rand.NewSource(1)), grammar.GenerateOptions{MaxSize: 10, Valid: never}); err == nil {
+		t.Error("returned a program that isn't valid")
+	}
+}
+
+func TestGenerateCabinScope(t *testing.T) {
+	g := load(t)
+	identifier := regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	for seed := range 200 {
+		program, err := g.Generate(rand.New(rand.NewSource(int64(seed))), grammar.GenerateOptions{Scope: grammar.CabinScope})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Names are declared before they're used, so a type after new has
+		// to be a builtin or appear earlier in the program.
+		words := strings.Fields(program)
+		for i := 0; i+1 < len(words); i++ {
+			if typ := words[i+1]; words[i] == "new" && identifier.MatchString(typ) &&
+				!slices.Contains(grammar.CabinScope.Builtins, typ) && !slices.Contains(words[:i], typ) {
+				t.Fatalf("seed %d: new %s before %s is declared: %s", seed, typ, typ, program)
+			}
+		}
+	}
+}
+
+func TestGenerateScope(t *testing.T) {
+	g, err := grammar.Parse([]byte(`{
+		"name": "scoped",
+		"rules": {
+			"program": {"type": "REPEAT", "content": {"type": "SYMBOL", "name": "statement"}},
+			"statement": {"type": "CHOICE", "members": [
+				{"type": "SYMBOL", "name": "let"},
+				{"type": "SYMBOL", "name": "use"},
+				{"type": "SYMBOL", "name": "block"}
+			]},
+			"let": {"type": "SEQ", "members": [
+				{"type": "STRING", "value": "let"},
+				{"type": "FIELD", "name": "name", "content": {"type": "SYMBOL", "name": "name"}},
+				{"type": "STRING", "value": "="},
+				{"type": "SYMBOL", "name": "use"}
+			]},
+			"use": {"type": "SEQ", "members": [
+				{"type": "STRING", "value": "use"},
+				{"type": "SYMBOL", "name": "name"}
+			]},
+			"block": {"type": "SEQ", "members": [
+				{"type": "STRING", "value": "{"},
+				{"type": "SYMBOL", "name": "program"},
+				{"type": "STRING", "value": "}"}
+			]},
+			"name": {"type": "PATTERN", "value": "[a-z]{1,2}"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := &grammar.Scope{
+		Identifier:   "name",
+		Declarations: []string{"let.name"},
+		References:   []string{"use"},
+		Blocks:       []string{"block"},
+		Builtins:     []string{"print"},
+	}
+
+	for seed := range 100 {
+		program, err := g.Generate(rand.New(rand.NewSource(int64(seed))), grammar.GenerateOptions{MaxDepth: 10, MaxSize: 100, Scope: scope})
+		if err != nil {
+			t.Fatal(err)
+		}
+		scopes := [][]string{{"print"}}
+		words := strings.Fields(program)
+		for i := 0; i < len(words); i++ {
+			switch words[i] {
+			case "{":
+				scopes = append(scopes, nil)
+			case "}":
+				scopes = scopes[:len(scopes)-1]
+			case "let":
+				// The name is in scope after the value, which can't use it.
+				name, value := words[i+1], words[i+4]
+				if !slices.ContainsFunc(scopes, func(names []string) bool { return slices.Contains(names, value) }) {
+					t.Fatalf("seed %d: %s isn't declared before it's used: %s", seed, value, program)
+				}
+				scopes[len(scopes)-1] = append(scopes[len(scopes)-1], name)
+				i += 4
+			case "use":
+				name := words[i+1]
+				if !slices.ContainsFunc(scopes, func(names []string) bool { return slices.Contains(names, name) }) {
+					t.Fatalf("seed %d: %s isn't declared before it's used: %s", seed, name, program)
+				}
+				i++
+			}
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	g, err := grammar.Parse([]byte(`{
+		"name": "errors",
+		"rules": {
+			"use": {"type": "SEQ", "members": [
+				{"type": "STRING", "value": "use"},
+				{"type": "SYMBOL", "name": "name"}
+			]},
+			"keyword": {"type": "PATTERN", "value": "use"},
+			"name": {"type": "PATTERN", "value": "[a-z]"}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := &grammar.Scope{Identifier: "name", References: []string{"use"}}
+	if _, err := g.Generate(rand.New(rand.NewSource(1)), grammar.GenerateOptions{Scope: scope}); err == nil {
+		t.Error("generated a reference with no name in scope")
+	}
+	if _, err := g.Generate(rand.New(rand.NewSource(1)), grammar.GenerateOptions{Start: "keyword"}); err == nil {
+		t.Error("generated a pattern that only matches a keyword")
+	}
+}
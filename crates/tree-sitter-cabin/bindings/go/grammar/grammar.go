@@ -0,0 +1,296 @@
+// Package grammar loads the grammar.json that tree-sitter generates from
+// grammar.js into typed rules. It draws railroad diagrams of them, so that
+// the language reference is generated from the grammar the parser is built
+// from, and generates random programs from them for tests.
+package grammar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A Grammar is a loaded grammar.json.
+type Grammar struct {
+	Name string
+	// Rules are the rules of the grammar in the order they're defined in.
+	Rules       []Definition
+	Extras      []Rule
+	Externals   []Rule
+	Precedences [][]Rule
+	Reserved    map[string][]Rule
+	Conflicts   [][]string
+	Inline      []string
+	Supertypes  []string
+	Word        string
+}
+
+// A Definition is a named rule of a grammar.
+type Definition struct {
+	Name string
+	Rule Rule
+}
+
+// Rule returns the rule called name, or nil if there isn't one.
+func (g *Grammar) Rule(name string) Rule {
+	for _, definition := range g.Rules {
+		if definition.Name == name {
+			return definition.Rule
+		}
+	}
+	return nil
+}
+
+// A Rule is a node of the rule tree of a grammar, one of the types below.
+type Rule interface {
+	rule()
+}
+
+// Blank matches nothing.
+type Blank struct{}
+
+// String matches its text literally.
+type String struct{ Value string }
+
+// Pattern matches a regular expression.
+type Pattern struct{ Value, Flags string }
+
+// Symbol matches the rule called Name.
+type Symbol struct{ Name string }
+
+// Seq matches its members one after the other.
+type Seq struct{ Members []Rule }
+
+// Choice matches one of its members.
+type Choice struct{ Members []Rule }
+
+// Repeat matches its content zero or more times.
+type Repeat struct{ Content Rule }
+
+// Repeat1 matches its content one or more times.
+type Repeat1 struct{ Content Rule }
+
+// Field gives the node its content matches a field name.
+type Field struct {
+	Name    string
+	Content Rule
+}
+
+// Alias matches its content as a node called Value, which is named or an
+// anonymous token.
+type Alias struct {
+	Value   string
+	Named   bool
+	Content Rule
+}
+
+// Token matches its content as a single token. An immediate token may not be
+// preceded by extras.
+type Token struct {
+	Immediate bool
+	Content   Rule
+}
+
+// Prec matches its content with a precedence, which is an integer or the name
+// of a level of the grammar's precedences. Kind is PREC, PREC_LEFT,
+// PREC_RIGHT or PREC_DYNAMIC.
+type Prec struct {
+	Kind    string
+	Value   any
+	Content Rule
+}
+
+// Reserved matches its content with the reserved words of a context.
+type Reserved struct {
+	Context string
+	Content Rule
+}
+
+func (Blank) rule()    {}
+func (String) rule()   {}
+func (Pattern) rule()  {}
+func (Symbol) rule()   {}
+func (Seq) rule()      {}
+func (Choice) rule()   {}
+func (Repeat) rule()   {}
+func (Repeat1) rule()  {}
+func (Field) rule()    {}
+func (Alias) rule()    {}
+func (Token) rule()    {}
+func (Prec) rule()     {}
+func (Reserved) rule() {}
+
+// Load loads the grammar.json at path.
+func Load(path string) (*Grammar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	grammar, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return grammar, nil
+}
+
+// jsonRule is a rule as grammar.json spells it.
+type jsonRule struct {
+	Type        string          `json:"type"`
+	Value       json.RawMessage `json:"value"`
+	Name        string          `json:"name"`
+	Flags       string          `json:"flags"`
+	Named       bool            `json:"named"`
+	ContextName string          `json:"context_name"`
+	Members     []jsonRule      `json:"members"`
+	Content     *jsonRule       `json:"content"`
+}
+
+// Parse parses the contents of a grammar.json.
+func Parse(data []byte) (*Grammar, error) {
+	var file struct {
+		Name        string                `json:"name"`
+		Rules       json.RawMessage       `json:"rules"`
+		Extras      []jsonRule            `json:"extras"`
+		Externals   []jsonRule            `json:"externals"`
+		Precedences [][]jsonRule          `json:"precedences"`
+		Reserved    map[string][]jsonRule `json:"reserved"`
+		Conflicts   [][]string            `json:"conflicts"`
+		Inline      []string              `json:"inline"`
+		Supertypes  []string              `json:"supertypes"`
+		Word        string                `json:"word"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	grammar := &Grammar{
+		Name:       file.Name,
+		Conflicts:  file.Conflicts,
+		Inline:     file.Inline,
+		Supertypes: file.Supertypes,
+		Word:       file.Word,
+	}
+
+	// The order of the rules matters, and a map would lose it.
+	decoder := json.NewDecoder(bytes.NewReader(file.Rules))
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("rules: %w", err)
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("rules: %w", err)
+		}
+		name, _ := token.(string)
+		var raw jsonRule
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		rule, err := convert(raw)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		grammar.Rules = append(grammar.Rules, Definition{Name: name, Rule: rule})
+	}
+
+	var err error
+	if grammar.Extras, err = convertAll(file.Extras); err != nil {
+		return nil, fmt.Errorf("extras: %w", err)
+	}
+	if grammar.Externals, err = convertAll(file.Externals); err != nil {
+		return nil, fmt.Errorf("externals: %w", err)
+	}
+	for _, level := range file.Precedences {
+		rules, err := convertAll(level)
+		if err != nil {
+			return nil, fmt.Errorf("precedences: %w", err)
+		}
+		grammar.Precedences = append(grammar.Precedences, rules)
+	}
+	if len(file.Reserved) > 0 {
+		grammar.Reserved = map[string][]Rule{}
+		for context, words := range file.Reserved {
+			if grammar.Reserved[context], err = convertAll(words); err != nil {
+				return nil, fmt.Errorf("reserved %s: %w", context, err)
+			}
+		}
+	}
+	return grammar, nil
+}
+
+func convertAll(raws []jsonRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(raws))
+	for _, raw := range raws {
+		rule, err := convert(raw)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func convert(raw jsonRule) (Rule, error) {
+	var content Rule
+	switch raw.Type {
+	case "REPEAT", "REPEAT1", "FIELD", "ALIAS", "TOKEN", "IMMEDIATE_TOKEN", "PREC", "PREC_LEFT", "PREC_RIGHT", "PREC_DYNAMIC", "RESERVED":
+		if raw.Content == nil {
+			return nil, fmt.Errorf("%s without content", raw.Type)
+		}
+		var err error
+		if content, err = convert(*raw.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	switch raw.Type {
+	case "BLANK":
+		return Blank{}, nil
+	case "STRING", "PATTERN":
+		var value string
+		if err := json.Unmarshal(raw.Value, &value); err != nil {
+			return nil, fmt.Errorf("%s value: %w", raw.Type, err)
+		}
+		if raw.Type == "STRING" {
+			return String{Value: value}, nil
+		}
+		return Pattern{Value: value, Flags: raw.Flags}, nil
+	case "SYMBOL":
+		return Symbol{Name: raw.Name}, nil
+	case "SEQ", "CHOICE":
+		members, err := convertAll(raw.Members)
+		if err != nil {
+			return nil, err
+		}
+		if raw.Type == "SEQ" {
+			return Seq{Members: members}, nil
+		}
+		return Choice{Members: members}, nil
+	case "REPEAT":
+		return Repeat{Content: content}, nil
+	case "REPEAT1":
+		return Repeat1{Content: content}, nil
+	case "FIELD":
+		return Field{Name: raw.Name, Content: content}, nil
+	case "ALIAS":
+		var value string
+		if err := json.Unmarshal(raw.Value, &value); err != nil {
+			return nil, fmt.Errorf("ALIAS value: %w", err)
+		}
+		return Alias{Value: value, Named: raw.Named, Content: content}, nil
+	case "TOKEN", "IMMEDIATE_TOKEN":
+		return Token{Immediate: raw.Type == "IMMEDIATE_TOKEN", Content: content}, nil
+	case "PREC", "PREC_LEFT", "PREC_RIGHT", "PREC_DYNAMIC":
+		var value any
+		if err := json.Unmarshal(raw.Value, &value); err != nil {
+			return nil, fmt.Errorf("%s value: %w", raw.Type, err)
+		}
+		if number, ok := value.(float64); ok {
+			value = int(number)
+		}
+		return Prec{Kind: raw.Type, Value: value, Content: content}, nil
+	case "RESERVED":
+		return Reserved{Context: raw.ContextName, Content: content}, nil
+	}
+	return nil, fmt.Errorf("unknown rule type %q", raw.Type)
+}
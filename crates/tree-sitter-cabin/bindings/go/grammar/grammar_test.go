@@ -0,0 +1,84 @@
+package grammar_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/grammar"
+)
+
+func load(t *testing.T) *grammar.Grammar {
+	t.Helper()
+	g, err := grammar.Load("../../../src/grammar.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestLoad(t *testing.T) {
+	g := load(t)
+	if g.Name != "cabin" {
+		t.Errorf("loaded grammar %q, want cabin", g.Name)
+	}
+	if len(g.Rules) == 0 || g.Rules[0].Name != "source_file" {
+		t.Fatalf("the first rule isn't source_file: %v", g.Rules)
+	}
+
+	want := grammar.Seq{Members: []grammar.Rule{
+		grammar.Field{Name: "label", Content: grammar.Symbol{Name: "identifier"}},
+		grammar.String{Value: "is"},
+		grammar.Field{Name: "value", Content: grammar.Symbol{Name: "expression"}},
+	}}
+	if got := g.Rule("goto"); !reflect.DeepEqual(got, want) {
+		t.Errorf("goto is %#v, want %#v", got, want)
+	}
+	if got, want := g.Rule("number"), (grammar.Pattern{Value: `-?\d+(\.\d+)?`}); got != want {
+		t.Errorf("number is %#v, want %#v", got, want)
+	}
+	if extras := g.Extras; len(extras) != 2 || extras[0] != (grammar.Symbol{Name: "comment"}) {
+		t.Errorf("extras are %#v", extras)
+	}
+	if g.Rule("missing") != nil {
+		t.Error("found a rule that doesn't exist")
+	}
+}
+
+func TestParse(t *testing.T) {
+	g, err := grammar.Parse([]byte(`{
+		"name": "test",
+		"word": "word",
+		"rules": {
+			"b": {"type": "PREC_LEFT", "value": 2, "content": {"type": "REPEAT1", "content": {"type": "SYMBOL", "name": "a"}}},
+			"a": {"type": "ALIAS", "value": "x", "named": true, "content": {"type": "IMMEDIATE_TOKEN", "content": {"type": "STRING", "value": "a"}}},
+			"word": {"type": "PREC", "value": "level", "content": {"type": "RESERVED", "context_name": "global", "content": {"type": "BLANK"}}}
+		},
+		"precedences": [[{"type": "STRING", "value": "level"}, {"type": "SYMBOL", "name": "b"}]]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, definition := range g.Rules {
+		names = append(names, definition.Name)
+	}
+	if want := []string{"b", "a", "word"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("rules are in the order %v, want %v", names, want)
+	}
+	if got, want := g.Rule("b"), (grammar.Prec{Kind: "PREC_LEFT", Value: 2, Content: grammar.Repeat1{Content: grammar.Symbol{Name: "a"}}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("b is %#v, want %#v", got, want)
+	}
+	if got, want := g.Rule("a"), (grammar.Alias{Value: "x", Named: true, Content: grammar.Token{Immediate: true, Content: grammar.String{Value: "a"}}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("a is %#v, want %#v", got, want)
+	}
+	if got, want := g.Rule("word"), (grammar.Prec{Kind: "PREC", Value: "level", Content: grammar.Reserved{Context: "global", Content: grammar.Blank{}}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("word is %#v, want %#v", got, want)
+	}
+	if len(g.Precedences) != 1 || len(g.Precedences[0]) != 2 || g.Word != "word" {
+		t.Errorf("precedences are %#v and the word is %q", g.Precedences, g.Word)
+	}
+
+	if _, err := grammar.Parse([]byte(`{"rules": {"a": {"type": "SOMETHING"}}}`)); err == nil {
+		t.Error("parsed an unknown rule type")
+	}
+}
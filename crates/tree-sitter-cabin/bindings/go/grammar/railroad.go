@@ -0,0 +1,260 @@
+package grammar
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// The dimensions of railroad diagrams, in pixels.
+const (
+	radius     = 8.0
+	boxHeight  = 24.0
+	boxPadding = 10.0
+	charWidth  = 7.5
+	labelWidth = 6.0
+	labelSpace = 14.0
+	gap        = 12.0
+	rowGap     = 8.0
+	margin     = 10.0
+)
+
+// style is the stylesheet of the diagrams.
+const style = `.railroad path { fill: none; stroke: #333; stroke-width: 1.5; }
+.railroad rect { stroke: #333; stroke-width: 1.5; }
+.railroad rect.terminal { fill: #ffd; }
+.railroad rect.pattern { fill: #eef; }
+.railroad rect.nonterminal { fill: #dfd; }
+.railroad text { font: 12px monospace; text-anchor: middle; }
+.railroad text.field { font: italic 10px sans-serif; text-anchor: start; fill: #666; }`
+
+// A box is a laid out part of a diagram. The track enters it on the left and
+// leaves it on the right, up pixels below its top and down pixels above its
+// bottom.
+type box struct {
+	width, up, down float64
+	draw            func(svg *strings.Builder, x, y float64)
+}
+
+var empty = &box{draw: func(*strings.Builder, float64, float64) {}}
+
+func number(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func path(svg *strings.Builder, format string, args ...float64) {
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = number(arg)
+	}
+	fmt.Fprintf(svg, `<path d="`+format+`"/>`, values...)
+}
+
+// label returns a box with text in it, linked to href if it's not empty.
+func label(text, class, href string) *box {
+	width := float64(len([]rune(text)))*charWidth + 2*boxPadding
+	return &box{width: width, up: boxHeight / 2, down: boxHeight / 2, draw: func(svg *strings.Builder, x, y float64) {
+		if href != "" {
+			fmt.Fprintf(svg, `<a href="%s">`, html.EscapeString(href))
+		}
+		rx := 0.0
+		if class != "nonterminal" {
+			rx = boxHeight / 2
+		}
+		fmt.Fprintf(svg, `<rect class="%s" x="%s" y="%s" width="%s" height="%s" rx="%s"/>`, class, number(x), number(y-boxHeight/2), number(width), number(boxHeight), number(rx))
+		fmt.Fprintf(svg, `<text x="%s" y="%s">%s</text>`, number(x+width/2), number(y+4), html.EscapeString(text))
+		if href != "" {
+			svg.WriteString(`</a>`)
+		}
+	}}
+}
+
+// sequence returns a box with items one after the other.
+func sequence(items []*box) *box {
+	var kept []*box
+	for _, item := range items {
+		if item.width > 0 {
+			kept = append(kept, item)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return empty
+	case 1:
+		return kept[0]
+	}
+	result := &box{width: gap * float64(len(kept)-1)}
+	for _, item := range kept {
+		result.width += item.width
+		result.up = max(result.up, item.up)
+		result.down = max(result.down, item.down)
+	}
+	result.draw = func(svg *strings.Builder, x, y float64) {
+		for i, item := range kept {
+			if i > 0 {
+				path(svg, "M%s %sh%s", x, y, gap)
+				x += gap
+			}
+			item.draw(svg, x, y)
+			x += item.width
+		}
+	}
+	return result
+}
+
+// choice returns a box with a track through each of items, stacked from the
+// top. An optional choice has a track that skips them all on top.
+func choice(items []*box, optional bool) *box {
+	if optional {
+		items = append([]*box{empty}, items...)
+	}
+	if len(items) == 1 {
+		return items[0]
+	}
+	inner := 0.0
+	for _, item := range items {
+		inner = max(inner, item.width)
+	}
+	// offsets are the distances from the top track to the track of each item.
+	offsets := make([]float64, len(items))
+	for i := 1; i < len(items); i++ {
+		offsets[i] = max(offsets[i-1]+items[i-1].down+rowGap+items[i].up, 2*radius)
+	}
+	last := len(items) - 1
+	result := &box{width: inner + 4*radius, up: items[0].up, down: offsets[last] + items[last].down}
+	result.draw = func(svg *strings.Builder, x, y float64) {
+		for i, item := range items {
+			end := x + 2*radius + item.width
+			if i == 0 {
+				path(svg, "M%s %sh%s", x, y, 2*radius)
+				item.draw(svg, x+2*radius, y)
+				path(svg, "M%s %sH%s", end, y, x+result.width)
+				continue
+			}
+			track := y + offsets[i]
+			path(svg, "M%s %sa%s %s 0 0 1 %s %sV%sa%s %s 0 0 0 %s %s",
+				x, y, radius, radius, radius, radius, track-radius, radius, radius, radius, radius)
+			item.draw(svg, x+2*radius, track)
+			path(svg, "M%s %sH%sa%s %s 0 0 0 %s %sV%sa%s %s 0 0 1 %s %s",
+				end, track, x+result.width-2*radius, radius, radius, radius, -radius, y+radius, radius, radius, radius, -radius)
+		}
+	}
+	return result
+}
+
+// loop returns a box that goes through item one or more times.
+func loop(item *box) *box {
+	back := max(item.down+rowGap, 2*radius)
+	result := &box{width: item.width + 2*radius, up: item.up, down: back}
+	result.draw = func(svg *strings.Builder, x, y float64) {
+		end := x + radius + item.width
+		path(svg, "M%s %sh%s", x, y, radius)
+		item.draw(svg, x+radius, y)
+		path(svg, "M%s %sh%s", end, y, radius)
+		path(svg, "M%s %sa%s %s 0 0 1 %s %sV%sa%s %s 0 0 1 %s %sH%sa%s %s 0 0 1 %s %sV%sa%s %s 0 0 1 %s %s",
+			end, y, radius, radius, radius, radius, y+back-radius, radius, radius, -radius, radius,
+			x+radius, radius, radius, -radius, -radius, y+radius, radius, radius, radius, -radius)
+	}
+	return result
+}
+
+// field returns item with the name of its field above it.
+func field(name string, item *box) *box {
+	text := name + ":"
+	width := max(item.width, float64(len(text))*labelWidth)
+	return &box{width: width, up: item.up + labelSpace, down: item.down, draw: func(svg *strings.Builder, x, y float64) {
+		fmt.Fprintf(svg, `<text class="field" x="%s" y="%s">%s</text>`, number(x), number(y-item.up-4), html.EscapeString(text))
+		item.draw(svg, x, y)
+		if width > item.width {
+			path(svg, "M%s %sH%s", x+item.width, y, x+width)
+		}
+	}}
+}
+
+// layout lays out the diagram of rule.
+func layout(rule Rule) *box {
+	switch rule := rule.(type) {
+	case String:
+		return label(rule.Value, "terminal", "")
+	case Pattern:
+		return label("/"+rule.Value+"/", "pattern", "")
+	case Symbol:
+		return label(rule.Name, "nonterminal", "#"+rule.Name)
+	case Seq:
+		items := make([]*box, len(rule.Members))
+		for i, member := range rule.Members {
+			items[i] = layout(member)
+		}
+		return sequence(items)
+	case Choice:
+		var items []*box
+		optional := false
+		for _, member := range rule.Members {
+			if _, ok := member.(Blank); ok {
+				optional = true
+			} else {
+				items = append(items, layout(member))
+			}
+		}
+		if len(items) == 0 {
+			return empty
+		}
+		return choice(items, optional)
+	case Repeat:
+		return choice([]*box{loop(layout(rule.Content))}, true)
+	case Repeat1:
+		return loop(layout(rule.Content))
+	case Field:
+		return field(rule.Name, layout(rule.Content))
+	case Alias:
+		if rule.Named {
+			return label(rule.Value, "nonterminal", "")
+		}
+		return label(rule.Value, "terminal", "")
+	case Token:
+		return layout(rule.Content)
+	case Prec:
+		return layout(rule.Content)
+	case Reserved:
+		return layout(rule.Content)
+	}
+	return empty
+}
+
+// Diagram returns a railroad diagram of rule as an SVG image. Symbols link to
+// #name, the anchor of their rule in HTML.
+func Diagram(rule Rule) []byte {
+	terminus := &box{width: radius, up: radius, down: radius, draw: func(svg *strings.Builder, x, y float64) {
+		path(svg, "M%s %sv%sM%s %sh%s", x, y-radius, 2*radius, x, y, radius)
+	}}
+	end := &box{width: radius, up: radius, down: radius, draw: func(svg *strings.Builder, x, y float64) {
+		path(svg, "M%s %sh%sv%sv%s", x, y, radius, -radius, 2*radius)
+	}}
+	diagram := sequence([]*box{terminus, layout(rule), end})
+
+	var svg strings.Builder
+	width, height := diagram.width+2*margin, diagram.up+diagram.down+2*margin
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" class="railroad" width="%s" height="%s" viewBox="0 0 %s %s">`,
+		number(width), number(height), number(width), number(height))
+	fmt.Fprintf(&svg, "<style>%s</style>", style)
+	diagram.draw(&svg, margin, margin+diagram.up)
+	svg.WriteString("</svg>\n")
+	return []byte(svg.String())
+}
+
+// HTML returns a page with a railroad diagram of each rule of the grammar, in
+// order, with an anchor named after the rule.
+func (g *Grammar) HTML() []byte {
+	var page strings.Builder
+	title := html.EscapeString(g.Name) + " grammar"
+	fmt.Fprintf(&page, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n</head>\n<body>\n<h1>%s</h1>\n", title, title)
+	for _, definition := range g.Rules {
+		name := html.EscapeString(definition.Name)
+		fmt.Fprintf(&page, "<section id=\"%s\">\n<h2>%s</h2>\n", name, name)
+		page.Write(Diagram(definition.Rule))
+		page.WriteString("</section>\n")
+	}
+	page.WriteString("</body>\n</html>\n")
+	return []byte(page.String())
+}
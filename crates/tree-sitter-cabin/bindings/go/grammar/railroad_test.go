@@ -0,0 +1,46 @@
+package grammar_test
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/grammar"
+)
+
+func TestDiagram(t *testing.T) {
+	g := load(t)
+	for _, definition := range g.Rules {
+		svg := grammar.Diagram(definition.Rule)
+		decoder := xml.NewDecoder(bytes.NewReader(svg))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("diagram of %s isn't well-formed: %v\n%s", definition.Name, err, svg)
+			}
+		}
+	}
+
+	svg := string(grammar.Diagram(g.Rule("goto")))
+	for _, want := range []string{`<a href="#identifier">`, `>is</text>`, `>label:</text>`, `>value:</text>`} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("diagram of goto doesn't contain %s:\n%s", want, svg)
+		}
+	}
+}
+
+func TestHTML(t *testing.T) {
+	g := load(t)
+	page := string(g.HTML())
+	for _, definition := range g.Rules {
+		if !strings.Contains(page, `<section id="`+definition.Name+`">`) {
+			t.Errorf("page has no section for %s", definition.Name)
+		}
+	}
+	if strings.Count(page, "<svg") != len(g.Rules) {
+		t.Errorf("page has %d diagrams, want %d", strings.Count(page, "<svg"), len(g.Rules))
+	}
+}
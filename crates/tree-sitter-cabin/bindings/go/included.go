@@ -0,0 +1,124 @@
+package tree_sitter_cabin
+
+import (
+	"bytes"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ParseIncluded parses the Cabin source in ranges of a host document, such as
+// the code blocks of a Markdown file, as a single source file. The ranges
+// must be in order and not overlap.
+//
+// The tree spans the host document, so the offsets and points of its nodes,
+// and of the diagnostics and highlights found from them, are those of the
+// host. A node continued across ranges spans the host text between them; Clip
+// splits its range into the parts that are Cabin. With no ranges, such as for
+// a document without Cabin blocks, the tree is empty. The returned tree must
+// be closed.
+func ParseIncluded(host []byte, ranges []tree_sitter.Range) (*tree_sitter.Tree, error) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(Language())); err != nil {
+		return nil, err
+	}
+	if len(ranges) == 0 {
+		// The parser reads no ranges as the whole document, so it's given
+		// a single empty one instead.
+		ranges = []tree_sitter.Range{{}}
+	}
+	if err := parser.SetIncludedRanges(ranges); err != nil {
+		return nil, err
+	}
+	return parser.Parse(host, nil), nil
+}
+
+// ByteRanges returns the ranges of host from the start to the end offset of
+// each span, with their points. Spans in order are converted in a single pass.
+func ByteRanges(host []byte, spans [][2]uint) []tree_sitter.Range {
+	ranges := make([]tree_sitter.Range, len(spans))
+	points := pointScanner{text: host}
+	for i, span := range spans {
+		if int(span[0]) < points.offset {
+			// The scanner only goes forward.
+			points = pointScanner{text: host}
+		}
+		ranges[i] = tree_sitter.Range{
+			StartByte:  span[0],
+			EndByte:    span[1],
+			StartPoint: points.at(int(span[0])),
+			EndPoint:   points.at(int(span[1])),
+		}
+	}
+	return ranges
+}
+
+// FencedBlocks returns the ranges of the contents of the fenced code blocks of
+// a Markdown document whose info string starts with language, such as
+// ```cabin. A block that isn't closed runs to the end of the document.
+func FencedBlocks(markdown []byte, language string) []tree_sitter.Range {
+	var spans [][2]uint
+	var fence []byte
+	start := 0
+	for offset := 0; offset < len(markdown); {
+		end := len(markdown)
+		if i := bytes.IndexByte(markdown[offset:], '\n'); i >= 0 {
+			end = offset + i + 1
+		}
+		line := markdown[offset:end]
+		indented := bytes.TrimLeft(line, " ")
+		if len(line)-len(indented) <= 3 {
+			if fence == nil {
+				if marker := fenceMarker(indented); marker != nil {
+					info := bytes.Fields(indented[len(marker):])
+					if len(info) > 0 && string(info[0]) == language {
+						fence, start = marker, end
+					}
+				}
+			} else if marker := fenceMarker(indented); marker != nil && marker[0] == fence[0] && len(marker) >= len(fence) && len(bytes.TrimSpace(indented[len(marker):])) == 0 {
+				spans = append(spans, [2]uint{uint(start), uint(offset)})
+				fence = nil
+			}
+		}
+		offset = end
+	}
+	if fence != nil {
+		spans = append(spans, [2]uint{uint(start), uint(len(markdown))})
+	}
+	return ByteRanges(markdown, spans)
+}
+
+// fenceMarker returns the run of three or more backticks or tildes that line
+// starts with, or nil.
+func fenceMarker(line []byte) []byte {
+	if len(line) == 0 || line[0] != '`' && line[0] != '~' {
+		return nil
+	}
+	n := 0
+	for n < len(line) && line[n] == line[0] {
+		n++
+	}
+	if n < 3 {
+		return nil
+	}
+	return line[:n]
+}
+
+// Clip returns the parts of r that are in the included ranges, in order.
+func Clip(r tree_sitter.Range, included []tree_sitter.Range) []tree_sitter.Range {
+	var parts []tree_sitter.Range
+	for _, include := range included {
+		if include.EndByte <= r.StartByte || include.StartByte >= r.EndByte {
+			continue
+		}
+		part := r
+		if include.StartByte > part.StartByte {
+			part.StartByte, part.StartPoint = include.StartByte, include.StartPoint
+		}
+		if include.EndByte < part.EndByte {
+			part.EndByte, part.EndPoint = include.EndByte, include.EndPoint
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
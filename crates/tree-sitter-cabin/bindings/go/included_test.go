@@ -0,0 +1,84 @@
+package tree_sitter_cabin_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const markdown = "# Example\n\n```cabin\nlet x = group {\n```\n\nSome text.\n\n~~~ cabin title\n  a: Number };\n~~~\n\n```rust\nlet y = 2;\n```\n"
+
+func TestFencedBlocks(t *testing.T) {
+	var got []string
+	for _, r := range tree_sitter_cabin.FencedBlocks([]byte(markdown), "cabin") {
+		got = append(got, fmt.Sprintf("%q %v-%v", markdown[r.StartByte:r.EndByte], r.StartPoint, r.EndPoint))
+	}
+	want := []string{
+		`"let x = group {\n" {3 0}-{4 0}`,
+		`"  a: Number };\n" {9 0}-{10 0}`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got blocks\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	unclosed := "```cabin\nlet x = 1;\n"
+	if blocks := tree_sitter_cabin.FencedBlocks([]byte(unclosed), "cabin"); len(blocks) != 1 || blocks[0].EndByte != uint(len(unclosed)) {
+		t.Errorf("unclosed block is %v, want it to run to the end", blocks)
+	}
+}
+
+func TestParseIncluded(t *testing.T) {
+	ranges := tree_sitter_cabin.FencedBlocks([]byte(markdown), "cabin")
+	tree, err := tree_sitter_cabin.ParseIncluded([]byte(markdown), ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+
+	root := tree.RootNode()
+	if root.HasError() {
+		t.Fatalf("the blocks don't parse as one source: %s", root.ToSexp())
+	}
+	var fields []string
+	for node := range tree_sitter_cabin.Descendants(root, "identifier") {
+		fields = append(fields, fmt.Sprintf("%s at %v", node.Utf8Text([]byte(markdown)), node.StartPosition()))
+	}
+	if want := "x at {3 4}, a at {9 2}, Number at {9 5}"; strings.Join(fields, ", ") != want {
+		t.Errorf("got identifiers %s, want %s", strings.Join(fields, ", "), want)
+	}
+
+	group := tree_sitter_cabin.Unwrap(root.Child(0).Child(0).ChildByFieldName("value"))
+	var parts []string
+	for _, part := range tree_sitter_cabin.Clip(group.Range(), ranges) {
+		parts = append(parts, fmt.Sprintf("%q", markdown[part.StartByte:part.EndByte]))
+	}
+	if want := `"group {\n", "  a: Number }"`; strings.Join(parts, ", ") != want {
+		t.Errorf("group clipped to %s, want %s", strings.Join(parts, ", "), want)
+	}
+}
+
+func TestParseIncludedNoBlocks(t *testing.T) {
+	prose := "# Example\n\nSome text, and no Cabin.\n"
+	ranges := tree_sitter_cabin.FencedBlocks([]byte(prose), "cabin")
+	tree, err := tree_sitter_cabin.ParseIncluded([]byte(prose), ranges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if root := tree.RootNode(); root.ChildCount() != 0 || root.HasError() {
+		t.Errorf("parsed a document without blocks to %s", root.ToSexp())
+	}
+}
+
+func TestParseIncludedOverlapping(t *testing.T) {
+	ranges := tree_sitter_cabin.ByteRanges([]byte("let x = 1;"), [][2]uint{{4, 8}, {0, 2}})
+	if _, err := tree_sitter_cabin.ParseIncluded([]byte("let x = 1;"), ranges); err == nil {
+		t.Error("parsed with ranges out of order")
+	}
+	if ranges[1].StartPoint != (tree_sitter.Point{}) || ranges[0].EndPoint.Column != 8 {
+		t.Errorf("ranges out of order have points %v", ranges)
+	}
+}
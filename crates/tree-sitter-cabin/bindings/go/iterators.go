@@ -0,0 +1,81 @@
+package tree_sitter_cabin
+
+import (
+	"iter"
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Descendants returns an iterator over the named descendants of node, in
+// depth-first order, whose kind is one of kinds. With no kinds, every named
+// descendant is yielded. The node itself is not included.
+//
+// The iterator owns a TreeCursor that is freed when the loop ends, including
+// when it exits early.
+func Descendants(node *tree_sitter.Node, kinds ...string) iter.Seq[*tree_sitter.Node] {
+	return func(yield func(*tree_sitter.Node) bool) {
+		cursor := node.Walk()
+		defer cursor.Close()
+
+		depth := 0
+		for {
+			if cursor.GotoFirstChild() {
+				depth++
+			} else {
+				for !cursor.GotoNextSibling() {
+					if depth == 0 || !cursor.GotoParent() {
+						return
+					}
+					depth--
+				}
+			}
+
+			current := cursor.Node()
+			if current.IsNamed() && (len(kinds) == 0 || slices.Contains(kinds, current.Kind())) {
+				if !yield(current) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Matches returns an iterator over the matches of query in node. The match is
+// only valid until the next iteration.
+//
+// The iterator owns a QueryCursor that is freed when the loop ends, including
+// when it exits early.
+func Matches(query *tree_sitter.Query, node *tree_sitter.Node, source []byte) iter.Seq[*tree_sitter.QueryMatch] {
+	return func(yield func(*tree_sitter.QueryMatch) bool) {
+		cursor := tree_sitter.NewQueryCursor()
+		defer cursor.Close()
+
+		matches := cursor.Matches(query, node, source)
+		for match := matches.Next(); match != nil; match = matches.Next() {
+			if !yield(match) {
+				return
+			}
+		}
+	}
+}
+
+// Captures returns an iterator over the captures of query in node, in the
+// order they appear in the source. Each step yields the match and the index
+// of the capture within it; the match is only valid until the next iteration.
+//
+// The iterator owns a QueryCursor that is freed when the loop ends, including
+// when it exits early.
+func Captures(query *tree_sitter.Query, node *tree_sitter.Node, source []byte) iter.Seq2[*tree_sitter.QueryMatch, uint] {
+	return func(yield func(*tree_sitter.QueryMatch, uint) bool) {
+		cursor := tree_sitter.NewQueryCursor()
+		defer cursor.Close()
+
+		captures := cursor.Captures(query, node, source)
+		for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+			if !yield(match, index) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tree_sitter_cabin_test
+
+import (
+	"reflect"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func parse(t *testing.T, source string) *tree_sitter.Tree {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	t.Cleanup(parser.Close)
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func TestDescendants(t *testing.T) {
+	source := "let a = 1; let b = group { x: Number }; c(a, b);"
+	tree := parse(t, source)
+
+	var names []string
+	for node := range tree_sitter_cabin.Descendants(tree.RootNode(), "identifier") {
+		names = append(names, node.Utf8Text([]byte(source)))
+	}
+	if want := []string{"a", "b", "x", "Number", "c", "a", "b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got identifiers %q, want %q", names, want)
+	}
+
+	var groups []string
+	for node := range tree_sitter_cabin.Descendants(tree.RootNode(), "group") {
+		groups = append(groups, node.Kind())
+		if !node.IsNamed() {
+			t.Errorf("yielded the anonymous group keyword")
+		}
+	}
+	if len(groups) != 1 {
+		t.Errorf("got %d groups, want 1", len(groups))
+	}
+
+	count := 0
+	for range tree_sitter_cabin.Descendants(tree.RootNode()) {
+		count++
+		if count == 2 {
+			break
+		}
+	}
+	if count != 2 {
+		t.Errorf("early exit yielded %d nodes, want 2", count)
+	}
+}
+
+func TestMatchesAndCaptures(t *testing.T) {
+	source := "f(1); g(2); h(3);"
+	tree := parse(t, source)
+	query, err := tree_sitter.NewQuery(tree.Language(), "(function_call callee: (expression) @callee)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer query.Close()
+
+	var callees []string
+	for match := range tree_sitter_cabin.Matches(query, tree.RootNode(), []byte(source)) {
+		callees = append(callees, match.Captures[0].Node.Utf8Text([]byte(source)))
+		if len(callees) == 2 {
+			break
+		}
+	}
+	if want := []string{"f", "g"}; !reflect.DeepEqual(callees, want) {
+		t.Errorf("got callees %q, want %q", callees, want)
+	}
+
+	callees = nil
+	for match, index := range tree_sitter_cabin.Captures(query, tree.RootNode(), []byte(source)) {
+		callees = append(callees, match.Captures[index].Node.Utf8Text([]byte(source)))
+	}
+	if want := []string{"f", "g", "h"}; !reflect.DeepEqual(callees, want) {
+		t.Errorf("got callees %q, want %q", callees, want)
+	}
+}
@@ -0,0 +1,178 @@
+package lint
+
+import (
+	"fmt"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/walk"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Comparisons is the rule that reports code whose parse depends on the
+// spacing around < and >.
+//
+// So that they don't collide with compile-time arguments, the grammar only
+// reads < as a comparison when a space follows it, and > when a space
+// precedes it: a < b is a comparison, but a<b is the start of a call like
+// f<Text>(x). The rule warns where the other reading was probably meant, and
+// suggests the unambiguous spacing, which is one space on each side of a
+// comparison and none inside compile-time arguments.
+var Comparisons Rule = comparisonRule{}
+
+type comparisonRule struct{}
+
+func (comparisonRule) Name() string {
+	return "comparison-spacing"
+}
+
+func (comparisonRule) Check(file *File) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(diagnostic Diagnostic) {
+		diagnostic.Fix = contained(file, diagnostic.Fix)
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	chained := map[uintptr]bool{}
+	walk.Inspect(file.Tree.RootNode(), func(node *tree_sitter.Node) bool {
+		switch node.Kind() {
+		case "function_call":
+			if diagnostic, ok := unclosedArguments(file, node); ok {
+				report(diagnostic)
+			}
+		case "binary":
+			if diagnostic, ok := chainedComparison(file, node); ok {
+				report(diagnostic)
+				chained[tree_sitter_cabin.Unwrap(node.ChildByFieldName("left")).Id()] = true
+			} else if diagnostic, ok := comparisonSpacing(file, node); ok && !chained[node.Id()] {
+				report(diagnostic)
+			}
+		case "ERROR":
+			if diagnostic, ok := unspacedComparison(file, node); ok {
+				report(diagnostic)
+			}
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// contained returns fix, or nil if one of its edits replaces the spacing
+// between two tokens that are in different ranges of an embedded file, since
+// the host text between the ranges isn't the rule's to respace.
+func contained(file *File, fix *Fix) *Fix {
+	if fix == nil {
+		return nil
+	}
+	for _, edit := range fix.Edits {
+		if !file.contiguous(edit.Start, edit.End) {
+			return nil
+		}
+	}
+	return fix
+}
+
+// unclosedArguments reports a<b, which is parsed as a callee with
+// compile-time arguments that are missing their >.
+func unclosedArguments(file *File, call *tree_sitter.Node) (Diagnostic, bool) {
+	// The call is exactly callee < argument >, with the > missing.
+	callee, argument := call.ChildByFieldName("callee"), call.ChildByFieldName("compile_time_arguments")
+	if callee == nil || argument == nil || call.ChildCount() != 4 || !call.Child(3).IsMissing() {
+		return Diagnostic{}, false
+	}
+	left, right := file.Text(callee), file.Text(argument)
+	return Diagnostic{
+		Rule:     "comparison-spacing",
+		Severity: Warning,
+		Message:  fmt.Sprintf("%s<%s is parsed as compile-time arguments, not a comparison", left, right),
+		Range:    call.Range(),
+		Fix: &Fix{
+			Message: fmt.Sprintf("Compare with %s < %s", left, right),
+			Edits:   []Edit{{Start: callee.EndByte(), End: argument.StartByte(), Text: " < "}},
+		},
+	}, true
+}
+
+// chainedComparison reports f< Text >(x), which is parsed as two comparisons,
+// (f < Text) > (x), rather than a call with compile-time arguments.
+func chainedComparison(file *File, node *tree_sitter.Node) (Diagnostic, bool) {
+	inner := tree_sitter_cabin.Unwrap(node.ChildByFieldName("left"))
+	right := node.ChildByFieldName("right")
+	if comparison(node) != " >" || inner == nil || inner.Kind() != "binary" || comparison(inner) != "< " || right == nil || right.Child(0) == nil || right.Child(0).Kind() != "(" {
+		return Diagnostic{}, false
+	}
+	callee, argument := inner.ChildByFieldName("left"), inner.ChildByFieldName("right")
+	return Diagnostic{
+		Rule:     "comparison-spacing",
+		Severity: Warning,
+		Message:  fmt.Sprintf("%s< %s >(...) is parsed as two comparisons, not compile-time arguments", file.Text(callee), file.Text(argument)),
+		Range:    node.Range(),
+		Fix: &Fix{
+			Message: fmt.Sprintf("Call with %s<%s>", file.Text(callee), file.Text(argument)),
+			Edits: []Edit{
+				{Start: callee.EndByte(), End: argument.StartByte(), Text: "<"},
+				{Start: argument.EndByte(), End: right.StartByte(), Text: ">"},
+			},
+		},
+	}, true
+}
+
+// comparisonSpacing reports a comparison with < or > that doesn't have
+// exactly one space on each side, such as a< b.
+func comparisonSpacing(file *File, node *tree_sitter.Node) (Diagnostic, bool) {
+	operator := comparison(node)
+	left, right := node.ChildByFieldName("left"), node.ChildByFieldName("right")
+	if operator == "" || left == nil || right == nil {
+		return Diagnostic{}, false
+	}
+	want := " " + bare(operator) + " "
+	if string(file.Source[left.EndByte():right.StartByte()]) == want {
+		return Diagnostic{}, false
+	}
+	return Diagnostic{
+		Rule:     "comparison-spacing",
+		Severity: Info,
+		Message:  fmt.Sprintf("write comparisons with %s as a%sb", bare(operator), want),
+		Range:    node.Range(),
+		Fix: &Fix{
+			Message: "Space the comparison",
+			Edits:   []Edit{{Start: left.EndByte(), End: right.StartByte(), Text: want}},
+		},
+	}, true
+}
+
+// unspacedComparison reports a>b, where > isn't a comparison without the
+// space before it, so the parser gives up at the >.
+func unspacedComparison(file *File, node *tree_sitter.Node) (Diagnostic, bool) {
+	first := node.Child(0)
+	if first == nil || first.Kind() != ">" || file.Text(first) != ">" || previousToken(node) == nil {
+		return Diagnostic{}, false
+	}
+	return Diagnostic{
+		Rule:     "comparison-spacing",
+		Severity: Warning,
+		Message:  "> is only a comparison with a space before it",
+		Range:    first.Range(),
+		Fix: &Fix{
+			Message: "Space the comparison",
+			Edits:   []Edit{{Start: first.StartByte(), End: first.EndByte(), Text: " > "}},
+		},
+	}, true
+}
+
+// comparison returns the < or > operator of a binary node, as the grammar
+// spells it, or "" if it has neither.
+func comparison(node *tree_sitter.Node) string {
+	for i := uint(0); i < node.ChildCount(); i++ {
+		if kind := node.Child(i).Kind(); kind == "< " || kind == " >" {
+			return kind
+		}
+	}
+	return ""
+}
+
+// bare returns the < or > operator without its space.
+func bare(operator string) string {
+	if operator == "< " {
+		return "<"
+	}
+	return ">"
+}
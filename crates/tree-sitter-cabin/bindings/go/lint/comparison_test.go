@@ -0,0 +1,38 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+)
+
+func TestComparisons(t *testing.T) {
+	for _, test := range []struct {
+		source, message, fixed string
+	}{
+		{"let x = a<b;", "1:9: warning: a<b is parsed as compile-time arguments, not a comparison (comparison-spacing)", "let x = a < b;"},
+		{"let x = f< Text >(1);", "1:9: warning: f< Text >(...) is parsed as two comparisons, not compile-time arguments (comparison-spacing)", "let x = f<Text>(1);"},
+		{"let x = a< b;", "1:9: info: write comparisons with < as a < b (comparison-spacing)", "let x = a < b;"},
+		{"let x = a  >  b;", "1:9: info: write comparisons with > as a > b (comparison-spacing)", "let x = a > b;"},
+		{"let x = a>b;", "1:10: warning: > is only a comparison with a space before it (comparison-spacing)", "let x = a > b;"},
+		{"let x = a < b;", "", "let x = a < b;"},
+		{"let x = f<Text>(1) > 2;", "", "let x = f<Text>(1) > 2;"},
+	} {
+		file, err := lint.Parse("main.cabin", []byte(test.source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diagnostics := lint.Run(file, lint.Comparisons)
+		message := ""
+		if len(diagnostics) > 0 {
+			message = diagnostics[0].String()
+		}
+		if len(diagnostics) > 1 || message != test.message {
+			t.Errorf("%q: got diagnostics %v, want %q", test.source, diagnostics, test.message)
+		}
+		if fixed := string(lint.Apply(file.Source, diagnostics)); fixed != test.fixed {
+			t.Errorf("%q: fixed to %q, want %q", test.source, fixed, test.fixed)
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,69 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestParseIncluded(t *testing.T) {
+	host := "Use\u00a0it like this:\n\n```cabin\nlet x = 1\n```\n\nand then:\n\n```cabin\nlet y =\u00a0x;\n```\n"
+	file, err := lint.ParseIncluded("README.md", []byte(host), tree_sitter_cabin.FencedBlocks([]byte(host), "cabin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diagnostics := lint.Run(file, lint.Syntax, lint.Whitespace)
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		`4:1: error: unexpected "let" (syntax)`,
+		"10:8: warning: invisible character U+00A0 (no-break space) (whitespace)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	fixed := string(lint.Apply(file.Source, diagnostics))
+	if want := "Use\u00a0it like this:\n\n```cabin\nlet x = 1;\n```\n\nand then:\n\n```cabin\nlet y = x;\n```\n"; fixed != want {
+		t.Errorf("fixed to %q, want %q", fixed, want)
+	}
+}
+
+func TestParseIncludedNoBlocks(t *testing.T) {
+	for _, ranges := range [][]tree_sitter.Range{nil, {}} {
+		file, err := lint.ParseIncluded("README.md", []byte("# Example\n\nSome\u00a0text.\n"), ranges)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diagnostics := lint.Run(file, lint.Syntax, lint.Whitespace); len(diagnostics) != 0 {
+			t.Errorf("reported %v in a document without blocks", diagnostics)
+		}
+		file.Close()
+	}
+}
+
+func TestComparisonAcrossBlocks(t *testing.T) {
+	// The comparison is split between the blocks, so respacing it would
+	// replace the fences between them.
+	host := "```cabin\nlet b = a\n```\n\n```cabin\n<  c;\n```\n"
+	file, err := lint.ParseIncluded("README.md", []byte(host), tree_sitter_cabin.FencedBlocks([]byte(host), "cabin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diagnostics := lint.Run(file, lint.Comparisons)
+	if len(diagnostics) != 1 || diagnostics[0].Fix != nil {
+		t.Fatalf("got diagnostics %v, want one without a fix", diagnostics)
+	}
+	if fixed := string(lint.Apply(file.Source, diagnostics)); fixed != host {
+		t.Errorf("fixed to %q", fixed)
+	}
+}
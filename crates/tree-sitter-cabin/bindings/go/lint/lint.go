@@ -0,0 +1,239 @@
+// Package lint runs lint rules over Cabin source files and applies their
+// fixes.
+//
+// Rules are written in Go by implementing Rule, or declaratively as query
+// files loaded with LoadRules. Syntax is the built-in rule for syntax errors.
+// Cabin embedded in another document is checked in place with ParseIncluded.
+package lint
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A Severity is how serious a diagnostic is.
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Info
+	Hint
+)
+
+var severityNames = []string{"error", "warning", "info", "hint"}
+
+func (s Severity) String() string {
+	if int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ParseSeverity parses the name of a severity, as returned by String.
+func ParseSeverity(name string) (Severity, error) {
+	if i := slices.Index(severityNames, strings.ToLower(name)); i >= 0 {
+		return Severity(i), nil
+	}
+	return 0, fmt.Errorf("unknown severity %q", name)
+}
+
+// A File is a parsed Cabin source file.
+type File struct {
+	Name   string
+	Source []byte
+	Tree   *tree_sitter.Tree
+	// Ranges are the parts of Source that are Cabin when it's embedded in
+	// another document, or nil if all of it is.
+	Ranges []tree_sitter.Range
+}
+
+// Parse parses source. The returned file must be closed.
+func Parse(name string, source []byte) (*File, error) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		return nil, err
+	}
+	return &File{Name: name, Source: source, Tree: parser.Parse(source, nil)}, nil
+}
+
+// ParseIncluded parses the Cabin source in ranges of host, such as the code
+// blocks of a Markdown file. Diagnostics are reported, and fixes applied, at
+// their offsets in host. The returned file must be closed.
+func ParseIncluded(name string, host []byte, ranges []tree_sitter.Range) (*File, error) {
+	tree, err := tree_sitter_cabin.ParseIncluded(host, ranges)
+	if err != nil {
+		return nil, err
+	}
+	if ranges == nil {
+		// A nil Ranges would make all of host Cabin.
+		ranges = []tree_sitter.Range{}
+	}
+	return &File{Name: name, Source: host, Tree: tree, Ranges: ranges}, nil
+}
+
+// Close frees the syntax tree of the file.
+func (f *File) Close() {
+	f.Tree.Close()
+}
+
+// includes reports whether offset is in the Cabin source of the file.
+func (f *File) includes(offset uint) bool {
+	if f.Ranges == nil {
+		return true
+	}
+	for _, r := range f.Ranges {
+		if r.StartByte <= offset && offset < r.EndByte {
+			return true
+		}
+	}
+	return false
+}
+
+// contiguous reports whether the source from start to end is all in one range
+// of Cabin source, so that an edit replacing it leaves the host alone.
+func (f *File) contiguous(start, end uint) bool {
+	if f.Ranges == nil {
+		return true
+	}
+	for _, r := range f.Ranges {
+		if r.StartByte <= start && end <= r.EndByte {
+			return true
+		}
+	}
+	return false
+}
+
+// reparse parses the source of the file with the fixes of diagnostics
+// applied. The ranges of an embedded file move with the edits before and in
+// them.
+func (f *File) reparse(diagnostics []Diagnostic) (*File, error) {
+	edits := fixEdits(diagnostics, len(f.Source))
+	source := applyEdits(f.Source, edits)
+	if f.Ranges == nil {
+		return Parse(f.Name, source)
+	}
+	spans := make([][2]uint, len(f.Ranges))
+	for i, r := range f.Ranges {
+		start, end := int(r.StartByte), int(r.EndByte)
+		for _, edit := range edits {
+			delta := len(edit.Text) - int(edit.End-edit.Start)
+			if edit.Start < r.StartByte {
+				start += delta
+			}
+			if edit.Start <= r.EndByte {
+				end += delta
+			}
+		}
+		spans[i] = [2]uint{uint(start), uint(end)}
+	}
+	return ParseIncluded(f.Name, source, tree_sitter_cabin.ByteRanges(source, spans))
+}
+
+// Text returns the source text of node.
+func (f *File) Text(node *tree_sitter.Node) string {
+	return node.Utf8Text(f.Source)
+}
+
+// An Edit replaces the bytes from Start to End with Text.
+type Edit struct {
+	Start, End uint
+	Text       string
+}
+
+// A Fix is a set of edits that resolves a diagnostic.
+type Fix struct {
+	Message string
+	Edits   []Edit
+}
+
+// A Diagnostic is a problem found by a rule.
+type Diagnostic struct {
+	Rule     string
+	Severity Severity
+	Message  string
+	Range    tree_sitter.Range
+	Fix      *Fix
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s (%s)", d.Range.StartPoint.Row+1, d.Range.StartPoint.Column+1, d.Severity, d.Message, d.Rule)
+}
+
+// A Rule checks a file for one kind of problem.
+type Rule interface {
+	Name() string
+	Check(file *File) []Diagnostic
+}
+
+// Run checks file with every rule and returns the diagnostics sorted by
+// position.
+func Run(file *File, rules ...Rule) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, rule := range rules {
+		diagnostics = append(diagnostics, rule.Check(file)...)
+	}
+	slices.SortStableFunc(diagnostics, func(a, b Diagnostic) int {
+		return cmp.Compare(a.Range.StartByte, b.Range.StartByte)
+	})
+	return diagnostics
+}
+
+// Apply applies the fixes of diagnostics to source. A fix that overlaps one
+// already applied is skipped; run the rules again on the result to fix the
+// rest. A fix whose own edits overlap, or that has an edit ending before it
+// starts or past the end of source, is malformed and always skipped.
+func Apply(source []byte, diagnostics []Diagnostic) []byte {
+	return applyEdits(source, fixEdits(diagnostics, len(source)))
+}
+
+// fixEdits returns the edits of the fixes of diagnostics that Apply applies
+// to a source of size bytes, in order.
+func fixEdits(diagnostics []Diagnostic, size int) []Edit {
+	var edits []Edit
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Fix == nil {
+			continue
+		}
+		overlaps := false
+		for i, edit := range diagnostic.Fix.Edits {
+			if edit.Start > edit.End || edit.End > uint(size) {
+				overlaps = true
+			}
+			for _, other := range diagnostic.Fix.Edits[:i] {
+				overlaps = overlaps || overlap(edit, other)
+			}
+			for _, applied := range edits {
+				overlaps = overlaps || overlap(edit, applied)
+			}
+		}
+		if !overlaps {
+			edits = append(edits, diagnostic.Fix.Edits...)
+		}
+	}
+	slices.SortFunc(edits, func(a, b Edit) int { return cmp.Compare(a.Start, b.Start) })
+	return edits
+}
+
+// overlap reports whether two edits touch the same bytes, or insert at the
+// same offset, so that applying both is ambiguous.
+func overlap(a, b Edit) bool {
+	return a.Start < b.End && b.Start < a.End || a.Start == b.Start
+}
+
+func applyEdits(source []byte, edits []Edit) []byte {
+	var out []byte
+	last := uint(0)
+	for _, edit := range edits {
+		out = append(out, source[last:edit.Start]...)
+		out = append(out, edit.Text...)
+		last = edit.End
+	}
+	return append(out, source[last:]...)
+}
@@ -0,0 +1,158 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+)
+
+func TestProjectRules(t *testing.T) {
+	rules, err := lint.LoadProjectRules("testdata/project")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lint.CloseRules(rules)
+	if len(rules) != 2 {
+		t.Fatalf("loaded %d rules, want 2", len(rules))
+	}
+	for _, rule := range rules {
+		if err := rule.RunTests(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	file, err := lint.Parse("main.cabin", []byte("let TestPrint = 1;\nsystem.terminal.print(TestPrint);\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []string
+	for _, diagnostic := range lint.Run(file, lint.Rules(rules)...) {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		"1:1: hint: test declarations should be actions (test-names)",
+		"2:1: warning: system.terminal.print is only allowed at runtime (prefer-write)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseRulesErrors(t *testing.T) {
+	for source, want := range map[string]string{
+		`[[rule]]
+name = "a"
+message = "m"
+query = "(nonexistent)"`: `rules.toml: rule "a": query 1:2: nonexistent`,
+		`[[rule]]
+name = "a"
+message = "m"
+query = "(number)"
+selector = "number"`: "exactly one of query and selector must be set",
+		`[[rule]]
+name = "a"
+mesage = "m"`: "unknown key rule.mesage",
+		`[[rule]]
+name = "a"
+message = "m"
+severity = "fatal"
+query = "(number)"`: `unknown severity "fatal"`,
+		`[[rule]]
+name = "a"
+message = "{{.missing}}"
+query = "(number) @number"`: `message: no capture named "missing"`,
+		`[[rule]]
+name = "a"
+message = "m"
+selector = "number"
+fix = "{{if .node}}{{.arg}}{{end}}"`: `fix: no capture named "arg"`,
+	} {
+		rules, err := lint.ParseRules("rules.toml", []byte(source))
+		lint.CloseRules(rules)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("got error %v, want it to contain %q", err, want)
+		}
+	}
+}
+
+func TestFailingRuleTest(t *testing.T) {
+	rules, err := lint.ParseRules("rules.toml", []byte(`
+[[rule]]
+name = "numbers"
+message = "number"
+query = "(number) @number"
+fix = "0"
+
+[[rule.test]]
+code = "let x = 1;"
+diagnostics = 2
+fixed = "let x = 1;"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lint.CloseRules(rules)
+	err = rules[0].RunTests()
+	if err == nil || !strings.Contains(err.Error(), "got 1 diagnostics, want 2") || !strings.Contains(err.Error(), `fixed code is "let x = 0;"`) {
+		t.Errorf("unexpected test result: %v", err)
+	}
+}
+
+func TestApplyMalformedFix(t *testing.T) {
+	source := []byte("let x = 1;")
+	diagnostics := []lint.Diagnostic{
+		{Fix: &lint.Fix{Edits: []lint.Edit{{Start: 4, End: 5, Text: "y"}, {Start: 4, End: 9, Text: "z = 2"}}}},
+		{Fix: &lint.Fix{Edits: []lint.Edit{{Start: 9, End: 8, Text: "3"}}}},
+		{Fix: &lint.Fix{Edits: []lint.Edit{{Start: 9, End: 11, Text: "3;"}}}},
+		{Fix: &lint.Fix{Edits: []lint.Edit{{Start: 20, End: 20, Text: "\n"}}}},
+		{Fix: &lint.Fix{Edits: []lint.Edit{{Start: 0, End: 3, Text: "const"}}}},
+	}
+	if got, want := string(lint.Apply(source, diagnostics)), "const x = 1;"; got != want {
+		t.Errorf("applied fixes to %q, want %q", got, want)
+	}
+}
+
+func TestTemplateMissingCapture(t *testing.T) {
+	// Each match has only one of the captures in the alternation.
+	rules, err := lint.ParseRules("rules.toml", []byte(`
+[[rule]]
+name = "values"
+message = "value {{.value}}"
+query = '[(number) @number (string) @string] @value'
+fix = "{{.number}}0"
+
+[[rule]]
+name = "numbers"
+message = "number {{.number}}"
+query = '[(number) @number (string) @string] @value'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lint.CloseRules(rules)
+	file, err := lint.Parse("main.cabin", []byte(`let x = 1; let y = "a";`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diagnostics := lint.Run(file, lint.Rules(rules)...)
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		"1:9: warning: value 1 (values)",
+		"1:9: warning: number 1 (numbers)",
+		`1:20: warning: value "a" (values)`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if fixed := string(lint.Apply(file.Source, diagnostics)); fixed != `let x = 10; let y = "a";` {
+		t.Errorf("fixed code is %q", fixed)
+	}
+}
@@ -0,0 +1,100 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/walk"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// NegativeLiterals is the rule that reports a subtraction spaced like a
+// negative number, such as x -1.
+//
+// The number token takes a - in front of it, so x -1 could be read as x
+// followed by the literal -1. The parser only lexes that where no operator
+// may follow x, which no rule of the grammar allows, so it's always a
+// subtraction, but one that reads like something else.
+var NegativeLiterals Rule = negativeLiteralRule{}
+
+type negativeLiteralRule struct{}
+
+func (negativeLiteralRule) Name() string {
+	return "negative-literal"
+}
+
+func (negativeLiteralRule) Check(file *File) []Diagnostic {
+	var diagnostics []Diagnostic
+	walk.Inspect(file.Tree.RootNode(), func(node *tree_sitter.Node) bool {
+		if node.Kind() != "binary" {
+			return true
+		}
+		left, right := node.ChildByFieldName("left"), node.ChildByFieldName("right")
+		if left == nil || right == nil || tree_sitter_cabin.Unwrap(right).Kind() != "number" {
+			return true
+		}
+		gap := string(file.Source[left.EndByte():right.StartByte()])
+		if strings.TrimSpace(gap) == "-" && strings.HasSuffix(gap, "-") && gap != "-" {
+			diagnostics = append(diagnostics, Diagnostic{
+				Rule:     "negative-literal",
+				Severity: Warning,
+				Message:  fmt.Sprintf("%s -%s is a subtraction, not %s followed by a negative number", file.Text(left), file.Text(right), file.Text(left)),
+				Range:    node.Range(),
+				Fix: &Fix{
+					Message: "Put a space after the -",
+					Edits:   []Edit{{Start: right.StartByte(), End: right.StartByte(), Text: " "}},
+				},
+			})
+		}
+		return true
+	})
+	return diagnostics
+}
+
+// NumberRanges is the rule that reports number literals that the type they're
+// annotated with can't hold. A Number is a 64-bit float, so the rule reports
+// literals too large or too small for one, and integers too large to hold
+// exactly.
+var NumberRanges Rule = numberRangeRule{}
+
+type numberRangeRule struct{}
+
+func (numberRangeRule) Name() string {
+	return "number-range"
+}
+
+// maxExactInteger is the largest integer such that it and every smaller
+// integer are exactly representable as a Number.
+var maxExactInteger = new(big.Rat).SetInt64(1 << 53)
+
+func (numberRangeRule) Check(file *File) []Diagnostic {
+	var diagnostics []Diagnostic
+	walk.Inspect(file.Tree.RootNode(), func(node *tree_sitter.Node) bool {
+		annotation, value := node.ChildByFieldName("type"), node.ChildByFieldName("value")
+		if annotation == nil || value == nil || strings.TrimSpace(file.Text(annotation)) != "Number" {
+			return true
+		}
+		number := tree_sitter_cabin.Unwrap(value)
+		if number.Kind() != "number" {
+			return true
+		}
+
+		diagnostic := Diagnostic{Rule: "number-range", Range: number.Range()}
+		var numberErr *tree_sitter_cabin.NumberError
+		if _, err := tree_sitter_cabin.NumberFloat64(number, file.Source); errors.As(err, &numberErr) {
+			diagnostic.Severity = Error
+			diagnostic.Message = fmt.Sprintf("%s: a Number is a 64-bit float", numberErr)
+		} else if exact, err := tree_sitter_cabin.DecodeNumber(number, file.Source); err == nil && exact.IsInt() && new(big.Rat).Abs(exact).Cmp(maxExactInteger) > 0 {
+			diagnostic.Severity = Warning
+			diagnostic.Message = fmt.Sprintf("number %s is rounded: a Number only holds integers up to 2^53 exactly", file.Text(number))
+		} else {
+			return true
+		}
+		diagnostics = append(diagnostics, diagnostic)
+		return true
+	})
+	return diagnostics
+}
@@ -0,0 +1,62 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+)
+
+func TestNegativeLiterals(t *testing.T) {
+	for source, want := range map[string]string{
+		"let y = x -1;":        "let y = x - 1;",
+		"let y = f(x\n\t-1);":  "let y = f(x\n\t- 1);",
+		"let y = x - 1;":       "",
+		"let y = x-1;":         "",
+		"let y = f(x, -1);":    "",
+		"let y = [1 -2.5, 3];": "let y = [1 - 2.5, 3];",
+	} {
+		file, err := lint.Parse("main.cabin", []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diagnostics := lint.Run(file, lint.NegativeLiterals)
+		switch {
+		case want == "" && len(diagnostics) > 0:
+			t.Errorf("%q: got diagnostics %v, want none", source, diagnostics)
+		case want != "" && len(diagnostics) != 1:
+			t.Errorf("%q: got diagnostics %v, want 1", source, diagnostics)
+		case want != "":
+			if fixed := string(lint.Apply(file.Source, diagnostics)); fixed != want {
+				t.Errorf("%q: fixed to %q, want %q", source, fixed, want)
+			}
+		}
+		file.Close()
+	}
+}
+
+func TestNumberRanges(t *testing.T) {
+	source := "let a: Number = 1" + strings.Repeat("0", 400) + ";\n" +
+		"let b: Number = 9007199254740993;\n" +
+		"let c: Number = 9007199254740992;\n" +
+		"let d: Text = 1" + strings.Repeat("0", 400) + ";\n" +
+		"let e: Number = -0." + strings.Repeat("0", 400) + "1;\n"
+	file, err := lint.Parse("main.cabin", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var got []string
+	for _, diagnostic := range lint.Run(file, lint.NumberRanges) {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		"1:17: error: number 1" + strings.Repeat("0", 400) + " is out of range: a Number is a 64-bit float (number-range)",
+		"2:17: warning: number 9007199254740993 is rounded: a Number only holds integers up to 2^53 exactly (number-range)",
+		"5:17: error: number -0." + strings.Repeat("0", 400) + "1 is out of range: a Number is a 64-bit float (number-range)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,365 @@
+package lint
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/BurntSushi/toml"
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/selector"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// RulesDir is the directory of a project that LoadProjectRules reads rule
+// files from.
+const RulesDir = "lints"
+
+// A QueryRule is a rule defined in a rule file. It reports every match of a
+// tree-sitter query or a selector. In a rule file, it looks like:
+//
+//	[[rule]]
+//	name = "prefer-write"
+//	severity = "warning"
+//	message = "{{.callee}} is only allowed at runtime"
+//	query = '(function_call callee: (expression) @callee (#eq? @callee "system.terminal.print"))'
+//	report = "callee"
+//	fix = "system.terminal.write"
+//
+//	[[rule.test]]
+//	code = 'system.terminal.print("hi");'
+//	diagnostics = 1
+//	fixed = 'system.terminal.write("hi");'
+//
+// Exactly one of query and selector must be set. A selector match has a
+// single capture, named "node".
+//
+// The message and fix are text/template templates executed with a map from
+// capture names to the text of the captured nodes, and may only refer to the
+// captures of the query. The diagnostic is reported on the capture named by
+// report, or on the largest captured node if report is empty, and the fix
+// replaces that same node. A match that leaves out a capture the message
+// refers to isn't reported, and one that leaves out a capture the fix refers
+// to is reported without a fix.
+type QueryRule struct {
+	RuleName    string `toml:"name"`
+	SeverityKey string `toml:"severity"`
+	MessageText string `toml:"message"`
+	Query       string `toml:"query"`
+	Selector    string `toml:"selector"`
+	Report      string `toml:"report"`
+	FixText     string `toml:"fix"`
+	Tests       []Test `toml:"test"`
+
+	severity Severity
+	query    *tree_sitter.Query
+	selector *selector.Selector
+	message  *template.Template
+	fix      *template.Template
+}
+
+// A Test is an example of code checked by a rule, with the number of
+// diagnostics expected and, optionally, the code after applying the fixes.
+type Test struct {
+	Code        string  `toml:"code"`
+	Diagnostics int     `toml:"diagnostics"`
+	Fixed       *string `toml:"fixed"`
+}
+
+// LoadProjectRules loads the rule files in the RulesDir of a project. A
+// project without one has no rules. The rules must be closed with CloseRules.
+func LoadProjectRules(project string) ([]*QueryRule, error) {
+	rules, err := LoadRules(filepath.Join(project, RulesDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return rules, err
+}
+
+// LoadRules loads every .toml rule file in dir. The rules must be closed with
+// CloseRules.
+func LoadRules(dir string) ([]*QueryRule, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var rules []*QueryRule
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			CloseRules(rules)
+			return nil, err
+		}
+		loaded, err := ParseRules(path, data)
+		if err != nil {
+			CloseRules(rules)
+			return nil, err
+		}
+		rules = append(rules, loaded...)
+	}
+	return rules, nil
+}
+
+// ParseRules parses and compiles the rules of a rule file. The name is only
+// used in errors. The rules must be closed with CloseRules.
+func ParseRules(name string, data []byte) ([]*QueryRule, error) {
+	var file struct {
+		Rules []*QueryRule `toml:"rule"`
+	}
+	metadata, err := toml.Decode(string(data), &file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown key %s", name, undecoded[0])
+	}
+	for _, rule := range file.Rules {
+		if err := rule.compile(); err != nil {
+			CloseRules(file.Rules)
+			return nil, fmt.Errorf("%s: rule %q: %w", name, rule.RuleName, err)
+		}
+	}
+	return file.Rules, nil
+}
+
+// Close frees the compiled query of the rule. The rule must not be used
+// afterwards.
+func (r *QueryRule) Close() {
+	if r.query != nil {
+		r.query.Close()
+		r.query = nil
+	}
+}
+
+// CloseRules closes every rule of a rule set, such as before loading it again.
+func CloseRules(rules []*QueryRule) {
+	for _, rule := range rules {
+		rule.Close()
+	}
+}
+
+func (r *QueryRule) compile() error {
+	if r.RuleName == "" {
+		return errors.New("missing name")
+	}
+	if r.MessageText == "" {
+		return errors.New("missing message")
+	}
+
+	var err error
+	r.severity = Warning
+	if r.SeverityKey != "" {
+		if r.severity, err = ParseSeverity(r.SeverityKey); err != nil {
+			return err
+		}
+	}
+	if r.message, err = template.New("message").Option("missingkey=error").Parse(r.MessageText); err != nil {
+		return err
+	}
+	if r.FixText != "" {
+		if r.fix, err = template.New("fix").Option("missingkey=error").Parse(r.FixText); err != nil {
+			return err
+		}
+	}
+
+	captures := []string{"node"}
+	switch {
+	case (r.Query == "") == (r.Selector == ""):
+		return errors.New("exactly one of query and selector must be set")
+	case r.Selector != "":
+		if r.selector, err = selector.Compile(r.Selector); err != nil {
+			return err
+		}
+	default:
+		query, queryErr := tree_sitter.NewQuery(tree_sitter.NewLanguage(tree_sitter_cabin.Language()), r.Query)
+		if queryErr != nil {
+			return fmt.Errorf("query %d:%d: %s", queryErr.Row+1, queryErr.Column+1, strings.TrimSpace(queryErr.Message))
+		}
+		r.query = query
+		if _, ok := query.CaptureIndexForName(r.Report); r.Report != "" && !ok {
+			return fmt.Errorf("report: no capture named %q", r.Report)
+		}
+		captures = query.CaptureNames()
+	}
+
+	for _, t := range []*template.Template{r.message, r.fix} {
+		if t == nil {
+			continue
+		}
+		for _, defined := range t.Templates() {
+			if name := unknownField(defined.Root, captures); name != "" {
+				return fmt.Errorf("%s: no capture named %q", t.Name(), name)
+			}
+		}
+	}
+	return nil
+}
+
+// unknownField returns the first field that node refers to, such as arg in
+// {{.arg}}, that isn't one of captures, or "" if there is none.
+func unknownField(node parse.Node, captures []string) string {
+	var children []parse.Node
+	switch node := node.(type) {
+	case *parse.FieldNode:
+		if !slices.Contains(captures, node.Ident[0]) {
+			return node.Ident[0]
+		}
+	case *parse.ListNode:
+		if node != nil {
+			children = node.Nodes
+		}
+	case *parse.ActionNode:
+		children = []parse.Node{node.Pipe}
+	case *parse.PipeNode:
+		if node != nil {
+			for _, command := range node.Cmds {
+				children = append(children, command)
+			}
+		}
+	case *parse.CommandNode:
+		children = node.Args
+	case *parse.ChainNode:
+		children = []parse.Node{node.Node}
+	case *parse.IfNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.RangeNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.WithNode:
+		children = []parse.Node{node.Pipe, node.List, node.ElseList}
+	case *parse.TemplateNode:
+		children = []parse.Node{node.Pipe}
+	}
+	for _, child := range children {
+		if name := unknownField(child, captures); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// Name returns the name of the rule.
+func (r *QueryRule) Name() string {
+	return r.RuleName
+}
+
+// Check reports every match of the rule in file.
+func (r *QueryRule) Check(file *File) []Diagnostic {
+	var diagnostics []Diagnostic
+	root := file.Tree.RootNode()
+	if r.selector != nil {
+		for node := range r.selector.All(root, file.Source) {
+			captures := map[string]string{"node": file.Text(node)}
+			if diagnostic, ok := r.diagnostic(node, captures); ok {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+		return diagnostics
+	}
+
+	names := r.query.CaptureNames()
+	for match := range tree_sitter_cabin.Matches(r.query, root, file.Source) {
+		captures := map[string]string{}
+		var reported *tree_sitter.Node
+		for _, capture := range match.Captures {
+			name := names[capture.Index]
+			if _, ok := captures[name]; !ok {
+				captures[name] = file.Text(&capture.Node)
+			}
+			node := capture.Node
+			switch {
+			case r.Report != "":
+				if name == r.Report && reported == nil {
+					reported = &node
+				}
+			case reported == nil || size(&node) > size(reported):
+				reported = &node
+			}
+		}
+		if reported == nil {
+			continue
+		}
+		if diagnostic, ok := r.diagnostic(reported, captures); ok {
+			diagnostics = append(diagnostics, diagnostic)
+		}
+	}
+	return diagnostics
+}
+
+func size(node *tree_sitter.Node) uint {
+	return node.EndByte() - node.StartByte()
+}
+
+// diagnostic returns the diagnostic of a match reported on node. It reports
+// false if the message can't be executed with the captures of the match.
+func (r *QueryRule) diagnostic(node *tree_sitter.Node, captures map[string]string) (Diagnostic, bool) {
+	message, err := execute(r.message, captures)
+	if err != nil {
+		return Diagnostic{}, false
+	}
+	diagnostic := Diagnostic{
+		Rule:     r.RuleName,
+		Severity: r.severity,
+		Message:  message,
+		Range:    node.Range(),
+	}
+	if r.fix != nil {
+		if text, err := execute(r.fix, captures); err == nil {
+			diagnostic.Fix = &Fix{
+				Message: "Replace with " + text,
+				Edits:   []Edit{{Start: node.StartByte(), End: node.EndByte(), Text: text}},
+			}
+		}
+	}
+	return diagnostic, true
+}
+
+func execute(t *template.Template, captures map[string]string) (string, error) {
+	var out strings.Builder
+	if err := t.Execute(&out, captures); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// RunTests checks the rule against its test cases.
+func (r *QueryRule) RunTests() error {
+	var errs []error
+	for i, test := range r.Tests {
+		file, err := Parse(fmt.Sprintf("test %d", i+1), []byte(test.Code))
+		if err != nil {
+			return err
+		}
+		diagnostics := r.Check(file)
+		file.Close()
+
+		if len(diagnostics) != test.Diagnostics {
+			errs = append(errs, fmt.Errorf("rule %q: test %d: got %d diagnostics, want %d", r.RuleName, i+1, len(diagnostics), test.Diagnostics))
+		}
+		if test.Fixed != nil {
+			if fixed := string(Apply([]byte(test.Code), diagnostics)); fixed != *test.Fixed {
+				errs = append(errs, fmt.Errorf("rule %q: test %d: fixed code is %q, want %q", r.RuleName, i+1, fixed, *test.Fixed))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var _ Rule = (*QueryRule)(nil)
+
+// Rules converts loaded rules to a []Rule for Run.
+func Rules(rules []*QueryRule) []Rule {
+	converted := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		converted = append(converted, rule)
+	}
+	return converted
+}
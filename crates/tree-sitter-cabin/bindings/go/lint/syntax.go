@@ -0,0 +1,262 @@
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/walk"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Syntax is the rule that reports syntax errors: ERROR nodes, and the tokens
+// that the parser assumed were missing.
+//
+// Common mistakes come with a fix, such as a missing ; or , or bracket, or a
+// keyword from another language. A fix is only offered if reparsing the fixed
+// source leaves fewer syntax errors.
+var Syntax Rule = syntaxRule{}
+
+type syntaxRule struct{}
+
+func (syntaxRule) Name() string {
+	return "syntax"
+}
+
+func (syntaxRule) Check(file *File) []Diagnostic {
+	root := file.Tree.RootNode()
+	if !root.HasError() {
+		return nil
+	}
+	errors := SyntaxErrors(file.Tree)
+
+	// Each fix is verified together with the ones offered before it, so
+	// that applying all of them doesn't fix the same error twice.
+	var diagnostics, fixed []Diagnostic
+	walk.Inspect(root, func(node *tree_sitter.Node) bool {
+		var diagnostic Diagnostic
+		var candidates []Fix
+		switch {
+		case node.IsMissing():
+			diagnostic.Message = fmt.Sprintf("missing %s", node.Kind())
+			// A named node stands for text, such as an identifier, that
+			// there's no telling.
+			if !node.IsNamed() {
+				candidates = []Fix{insertToken(file.Source, node.StartByte(), node.Kind())}
+			}
+		case node.IsError():
+			diagnostic.Message = unexpected(file, node)
+			candidates = syntaxFixes(file, node)
+		default:
+			return node.HasError()
+		}
+		diagnostic.Rule = "syntax"
+		diagnostic.Severity = Error
+		diagnostic.Range = node.Range()
+		diagnostic.Fix, errors = bestFix(file, fixed, errors, candidates)
+		if diagnostic.Fix != nil {
+			fixed = append(fixed, diagnostic)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+		return false
+	})
+	return diagnostics
+}
+
+// SyntaxErrors returns the number of ERROR and missing nodes in tree.
+func SyntaxErrors(tree *tree_sitter.Tree) int {
+	count := 0
+	walk.Inspect(tree.RootNode(), func(node *tree_sitter.Node) bool {
+		if node.IsError() || node.IsMissing() {
+			count++
+		}
+		return node.HasError()
+	})
+	return count
+}
+
+func unexpected(file *File, node *tree_sitter.Node) string {
+	text, _, _ := strings.Cut(strings.TrimSpace(file.Text(node)), "\n")
+	if fields := strings.Fields(text); len(fields) > 0 {
+		text = fields[0]
+	}
+	if text == "" {
+		return "syntax error"
+	}
+	return fmt.Sprintf("unexpected %q", text)
+}
+
+// keywords maps the keywords of other languages that Cabin spells
+// differently to the Cabin ones.
+var keywords = map[string]string{
+	"else":     "otherwise",
+	"fn":       "action",
+	"function": "action",
+}
+
+var keywordPattern = regexp.MustCompile(`\b(else|fn|function)\b`)
+
+var closers = map[string]string{"{": "}", "(": ")", "[": "]"}
+
+// syntaxFixes returns the fixes worth trying for an ERROR node, in order of
+// preference.
+func syntaxFixes(file *File, node *tree_sitter.Node) []Fix {
+	var fixes []Fix
+	start, end := node.StartByte(), node.EndByte()
+	for _, match := range keywordPattern.FindAllIndex(file.Source[start:end], -1) {
+		word := string(file.Source[start+uint(match[0]) : start+uint(match[1])])
+		fixes = append(fixes, Fix{
+			Message: fmt.Sprintf("Replace %s with %s", word, keywords[word]),
+			Edits:   []Edit{{Start: start + uint(match[0]), End: start + uint(match[1]), Text: keywords[word]}},
+		})
+	}
+
+	// The token before the ERROR, or its last token, is usually the one
+	// that a separator is missing after.
+	var ends []uint
+	if previous := previousToken(node); previous != nil {
+		ends = append(ends, previous.EndByte())
+	}
+	if last := lastToken(node); last != nil {
+		ends = append(ends, last.EndByte())
+	}
+	for _, separator := range []string{";", ","} {
+		for _, at := range ends {
+			fixes = append(fixes, insert(at, separator))
+		}
+	}
+
+	// Unclosed brackets are closed at the end of the ERROR, or of what
+	// contains it, where the statement they're in may need a ; too. If the
+	// ERROR ends with a ;, the innermost one is likely closed before it
+	// instead, as in f(1;.
+	if open := unclosed(node); len(open) > 0 {
+		var close strings.Builder
+		for i := len(open) - 1; i >= 0; i-- {
+			close.WriteString(closers[open[i].Kind()])
+		}
+		for _, at := range []uint{end, lastEnd(node.Parent())} {
+			fixes = append(fixes, insert(at, close.String()), insert(at, close.String()+";"))
+		}
+		if last := lastToken(node); last != nil && last.Kind() == ";" {
+			innermost, rest := close.String()[:1], close.String()[1:]
+			for _, rest := range []string{rest, rest + ";"} {
+				fix := insert(last.StartByte(), innermost)
+				if rest != "" {
+					fix.Message += " and " + rest
+					fix.Edits = append(fix.Edits, Edit{Start: end, End: end, Text: rest})
+				}
+				fixes = append(fixes, fix)
+			}
+		}
+	}
+	return fixes
+}
+
+// lastEnd returns the end of the last token of node, or 0 if node is nil or
+// has none.
+func lastEnd(node *tree_sitter.Node) uint {
+	if node == nil {
+		return 0
+	}
+	if last := lastToken(node); last != nil {
+		return last.EndByte()
+	}
+	return 0
+}
+
+func insert(at uint, text string) Fix {
+	return Fix{Message: "Insert " + text, Edits: []Edit{{Start: at, End: at, Text: text}}}
+}
+
+// tight are the tokens written without a space before them.
+var tight = map[string]bool{";": true, ",": true, ".": true, "::": true, ")": true, "]": true, "}": true}
+
+// insertToken returns a fix inserting a token at an offset of source, with
+// spaces around it where it would otherwise run into its neighbours.
+func insertToken(source []byte, at uint, token string) Fix {
+	space := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+	text := token
+	if !tight[token] {
+		if at > 0 && !space(source[at-1]) && !strings.ContainsRune("([{", rune(source[at-1])) {
+			text = " " + text
+		}
+		if int(at) < len(source) && !space(source[at]) && !tight[string(source[at])] {
+			text += " "
+		}
+	}
+	fix := insert(at, text)
+	fix.Message = "Insert " + token
+	return fix
+}
+
+// unclosed returns the brackets opened in node that it doesn't close.
+func unclosed(node *tree_sitter.Node) []*tree_sitter.Node {
+	var open []*tree_sitter.Node
+	walk.Inspect(node, func(node *tree_sitter.Node) bool {
+		if node.ChildCount() > 0 {
+			return !node.IsExtra() || node.IsError()
+		}
+		kind := node.Kind()
+		if _, ok := closers[kind]; ok {
+			open = append(open, node)
+		} else if len(open) > 0 && closers[open[len(open)-1].Kind()] == kind && !node.IsMissing() {
+			open = open[:len(open)-1]
+		}
+		return false
+	})
+	return open
+}
+
+// previousToken returns the last token before node that isn't an extra.
+func previousToken(node *tree_sitter.Node) *tree_sitter.Node {
+	for ; node != nil; node = node.Parent() {
+		previous := node.PrevSibling()
+		for previous != nil && previous.IsExtra() {
+			previous = previous.PrevSibling()
+		}
+		if previous != nil {
+			return lastToken(previous)
+		}
+	}
+	return nil
+}
+
+// lastToken returns the last token of node that isn't an extra, or nil if
+// it has none.
+func lastToken(node *tree_sitter.Node) *tree_sitter.Node {
+	if node.ChildCount() == 0 {
+		if node.IsExtra() || node.IsMissing() {
+			return nil
+		}
+		return node
+	}
+	for i := int(node.ChildCount()) - 1; i >= 0; i-- {
+		child := node.Child(uint(i))
+		if child.IsExtra() && !child.IsError() {
+			continue
+		}
+		if last := lastToken(child); last != nil {
+			return last
+		}
+	}
+	return nil
+}
+
+// bestFix reparses the file with the fixed diagnostics and each candidate
+// applied, and returns the candidate that leaves the fewest syntax errors with
+// their number, or nil and errors if none leaves fewer than errors.
+func bestFix(original *File, fixed []Diagnostic, errors int, candidates []Fix) (*Fix, int) {
+	var best *Fix
+	for i := range candidates {
+		file, err := original.reparse(append(fixed[:len(fixed):len(fixed)], Diagnostic{Fix: &candidates[i]}))
+		if err != nil {
+			return nil, errors
+		}
+		if remaining := SyntaxErrors(file.Tree); remaining < errors {
+			best, errors = &candidates[i], remaining
+		}
+		file.Close()
+	}
+	return best, errors
+}
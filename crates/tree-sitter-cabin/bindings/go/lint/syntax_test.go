@@ -0,0 +1,76 @@
+package lint_test
+
+import (
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+)
+
+func TestSyntaxFixes(t *testing.T) {
+	for source, want := range map[string]string{
+		"let x = 1\nlet y = 2;\n":                 "let x = 1;\nlet y = 2;\n",
+		"let x = if a { 1; } else { 2; };\n":      "let x = if a { 1; } otherwise { 2; };\n",
+		"let x = if a { 1; } else { 2; }\n":       "let x = if a { 1; } otherwise { 2; };\n",
+		"let f = fn { };\n":                       "let f = action { };\n",
+		"let f = function { };\n":                 "let f = action { };\n",
+		"let x = match y { 1 => 2 3 => 4 };\n":    "let x = match y { 1 => 2, 3 => 4 };\n",
+		"let x = [1, 2;\n":                        "let x = [1, 2];\n",
+		"f(1;\n":                                  "f(1);\n",
+		"let x = { let y = 1;\n":                  "let x = { let y = 1;};\n",
+		"let x = 1;\nsystem.terminal.print(x);\n": "let x = 1;\nsystem.terminal.print(x);\n",
+		"let x = new Point { x = };\n":            "let x = new Point { x = action };\n",
+	} {
+		file, err := lint.Parse("main.cabin", []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		diagnostics := lint.Run(file, lint.Syntax)
+		if got := string(lint.Apply(file.Source, diagnostics)); got != want {
+			t.Errorf("fixing %q gave %q, want %q", source, got, want)
+		}
+		if fixed, err := lint.Parse("main.cabin", []byte(want)); err == nil {
+			if errors := lint.SyntaxErrors(fixed.Tree); errors != 0 {
+				t.Errorf("%q has %d syntax errors", want, errors)
+			}
+			fixed.Close()
+		}
+		file.Close()
+	}
+}
+
+func TestSyntaxDiagnostics(t *testing.T) {
+	file, err := lint.Parse("main.cabin", []byte("let x = if a { 1; } else { 2; };\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diagnostics := lint.Run(file, lint.Syntax)
+	if len(diagnostics) != 2 {
+		t.Fatalf("got diagnostics %v, want 2", diagnostics)
+	}
+	if got, want := diagnostics[0].String(), `1:20: error: unexpected "else" (syntax)`; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if fix := diagnostics[0].Fix; fix == nil || fix.Message != "Replace else with otherwise" {
+		t.Errorf("got fix %v, want else replaced with otherwise", fix)
+	}
+	// Once else is replaced, nothing is left to fix at the end of the line.
+	if fix := diagnostics[1].Fix; fix != nil {
+		t.Errorf("got fix %v for %s, want none", fix, diagnostics[1])
+	}
+}
+
+func TestSyntaxMissingName(t *testing.T) {
+	file, err := lint.Parse("main.cabin", []byte("let = 1;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// There's no telling which name is missing, so nothing is inserted.
+	diagnostics := lint.Run(file, lint.Syntax)
+	if len(diagnostics) != 1 || diagnostics[0].Fix != nil {
+		t.Errorf("got diagnostics %v, want one without a fix", diagnostics)
+	}
+}
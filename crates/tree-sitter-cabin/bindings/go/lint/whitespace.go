@@ -0,0 +1,120 @@
+package lint
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Whitespace is the rule that reports invisible and exotic whitespace: the
+// characters other than spaces, tabs and line breaks that the grammar skips
+// as whitespace, such as U+200B ZERO WIDTH SPACE, U+00A0 NO-BREAK SPACE or a
+// form feed.
+// Editors and diffs render them invisibly or as plain spaces. A byte order
+// mark is only allowed at the very start of a file.
+//
+// The fix removes zero-width characters next to other whitespace or the end
+// of the file, and replaces the others with a space, or a line break for
+// vertical tabs, form feeds, U+2028 and U+2029. A zero-width character between
+// two tokens separates them, so it's replaced with a space too.
+// Inside a string the character is part of the value, so it's reported
+// without a fix.
+var Whitespace Rule = whitespaceRule{}
+
+type whitespaceRule struct{}
+
+func (whitespaceRule) Name() string {
+	return "whitespace"
+}
+
+// whitespaceNames names the characters that the grammar skips besides the
+// Unicode space separators, with their replacement. Zero-width characters
+// have none.
+var whitespaceNames = map[rune]struct{ name, replacement string }{
+	'\v':     {"vertical tab", "\n"},
+	'\f':     {"form feed", "\n"},
+	'\u00a0': {"no-break space", " "},
+	'\u200b': {"zero width space", ""},
+	'\u2028': {"line separator", "\n"},
+	'\u2029': {"paragraph separator", "\n"},
+	'\u2060': {"word joiner", ""},
+	'\ufeff': {"byte order mark", ""},
+}
+
+func (whitespaceRule) Check(file *File) []Diagnostic {
+	var diagnostics []Diagnostic
+	root := file.Tree.RootNode()
+	row, column := uint(0), uint(0)
+	for offset := 0; offset < len(file.Source); {
+		r, size := utf8.DecodeRune(file.Source[offset:])
+		name, replacement, ok := exoticWhitespace(r)
+		if ok && !(r == '\ufeff' && offset == 0) && file.includes(uint(offset)) {
+			start, end := uint(offset), uint(offset+size)
+			diagnostic := Diagnostic{
+				Rule:     "whitespace",
+				Severity: Warning,
+				Message:  fmt.Sprintf("invisible character U+%04X (%s)", r, name),
+				Range: tree_sitter.Range{
+					StartByte:  start,
+					EndByte:    end,
+					StartPoint: tree_sitter.NewPoint(row, column),
+					EndPoint:   tree_sitter.NewPoint(row, column+uint(size)),
+				},
+			}
+			if !inString(root, start, end) {
+				if replacement == "" && !nextToSpace(file.Source, start, end) {
+					replacement = " "
+				}
+				message := "Remove it"
+				if replacement != "" {
+					message = fmt.Sprintf("Replace it with %q", replacement)
+				}
+				diagnostic.Fix = &Fix{Message: message, Edits: []Edit{{Start: start, End: end, Text: replacement}}}
+			}
+			diagnostics = append(diagnostics, diagnostic)
+		}
+
+		if r == '\n' {
+			row, column = row+1, 0
+		} else {
+			column += uint(size)
+		}
+		offset += size
+	}
+	return diagnostics
+}
+
+// exoticWhitespace returns the name of r and what to replace it with if it's
+// whitespace other than a space, tab or line break.
+func exoticWhitespace(r rune) (name, replacement string, ok bool) {
+	if known, ok := whitespaceNames[r]; ok {
+		return known.name, known.replacement, true
+	}
+	if r != ' ' && unicode.Is(unicode.Zs, r) {
+		return "space", " ", true
+	}
+	return "", "", false
+}
+
+// nextToSpace reports whether the bytes from start to end of source follow or
+// precede a space, tab or line break, or are at the start or end of source.
+func nextToSpace(source []byte, start, end uint) bool {
+	space := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+	return start == 0 || end == uint(len(source)) || space(source[start-1]) || space(source[end])
+}
+
+// inString reports whether the bytes from start to end are in the text of a
+// string, rather than in an interpolated expression or between tokens.
+func inString(root *tree_sitter.Node, start, end uint) bool {
+	for node := root.DescendantForByteRange(start, end); node != nil; node = node.Parent() {
+		switch node.Kind() {
+		case "string", "raw_string":
+			return true
+		case "expression":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package lint_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+)
+
+func TestWhitespace(t *testing.T) {
+	source := "\ufefflet x =\u00a01;\nlet\u200b y = x;\u2028let z = \"a\u2003b\";\nlet w =\u3000`\ufeff`;\n\vlet v = w;\f\n"
+	file, err := lint.Parse("main.cabin", []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	diagnostics := lint.Run(file, lint.Whitespace)
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.String())
+	}
+	want := []string{
+		"1:11: warning: invisible character U+00A0 (no-break space) (whitespace)",
+		"2:4: warning: invisible character U+200B (zero width space) (whitespace)",
+		"2:14: warning: invisible character U+2028 (line separator) (whitespace)",
+		"2:27: warning: invisible character U+2003 (space) (whitespace)",
+		"3:8: warning: invisible character U+3000 (space) (whitespace)",
+		"3:12: warning: invisible character U+FEFF (byte order mark) (whitespace)",
+		"4:1: warning: invisible character U+000B (vertical tab) (whitespace)",
+		"4:12: warning: invisible character U+000C (form feed) (whitespace)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got diagnostics\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	fixed := string(lint.Apply(file.Source, diagnostics))
+	if want := "\ufefflet x = 1;\nlet y = x;\nlet z = \"a\u2003b\";\nlet w = `\ufeff`;\n\nlet v = w;\n\n"; fixed != want {
+		t.Errorf("fixed to %q, want %q", fixed, want)
+	}
+}
+
+func TestWhitespaceBetweenTokens(t *testing.T) {
+	for source, want := range map[string]string{
+		"let x = a\u2060or\u2060b;": "let x = a or b;",
+		"let\u200bx = 1;":           "let x = 1;",
+		"let x =\u200b 1;\u200b":    "let x = 1;",
+	} {
+		file, err := lint.Parse("main.cabin", []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fixed := string(lint.Apply(file.Source, lint.Run(file, lint.Whitespace))); fixed != want {
+			t.Errorf("fixed %q to %q, want %q", source, fixed, want)
+		}
+		file.Close()
+	}
+}
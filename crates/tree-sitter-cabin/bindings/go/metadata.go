@@ -0,0 +1,69 @@
+package tree_sitter_cabin
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// LanguageMetadata is what tree-sitter.json declares about the grammar.
+type LanguageMetadata struct {
+	// Name is the name of the grammar.
+	Name string
+	// Scope is the TextMate scope of Cabin source.
+	Scope string
+	// FileTypes are the file name suffixes of Cabin files.
+	FileTypes []string
+	// InjectionRegex matches the language names that other grammars inject
+	// Cabin for, such as the info string of a Markdown code block.
+	InjectionRegex string
+	// Version is the version of the grammar.
+	Version string
+}
+
+// Metadata returns the metadata of the grammar. It's kept in sync with
+// tree-sitter.json by a test.
+func Metadata() LanguageMetadata {
+	return LanguageMetadata{
+		Name:           "cabin",
+		Scope:          "source.cabin",
+		FileTypes:      []string{".cabin"},
+		InjectionRegex: "^cabin$",
+		Version:        "0.1.0",
+	}
+}
+
+// Detect reports whether a file is Cabin source, given its path and its first
+// line: if the path ends in one of the file types of the grammar, or the first
+// line is a #! line that runs cabin.
+func Detect(path, firstLine string) bool {
+	name := filepath.Base(path)
+	if slices.ContainsFunc(Metadata().FileTypes, func(fileType string) bool {
+		return strings.HasSuffix(name, fileType)
+	}) {
+		return true
+	}
+	interpreter, ok := strings.CutPrefix(strings.TrimSpace(firstLine), "#!")
+	if !ok {
+		return false
+	}
+	fields := strings.Fields(interpreter)
+	if len(fields) > 0 && filepath.Base(fields[0]) == "env" {
+		fields = slices.DeleteFunc(fields[1:], func(field string) bool { return strings.HasPrefix(field, "-") })
+	}
+	return len(fields) > 0 && filepath.Base(fields[0]) == "cabin"
+}
+
+// CheckABI returns an error if the parser was generated for a version of the
+// tree-sitter ABI that the linked go-tree-sitter runtime can't load.
+func CheckABI() error {
+	version := tree_sitter.NewLanguage(Language()).AbiVersion()
+	if version < tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION || version > tree_sitter.LANGUAGE_VERSION {
+		return fmt.Errorf("the Cabin parser was generated for tree-sitter ABI version %d, but the linked go-tree-sitter runtime supports versions %d to %d: regenerate the parser or change the version of go-tree-sitter",
+			version, tree_sitter.MIN_COMPATIBLE_LANGUAGE_VERSION, tree_sitter.LANGUAGE_VERSION)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package tree_sitter_cabin_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestMetadata(t *testing.T) {
+	data, err := os.ReadFile("../../tree-sitter.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config struct {
+		Grammars []struct {
+			Name           string   `json:"name"`
+			Scope          string   `json:"scope"`
+			FileTypes      []string `json:"file-types"`
+			InjectionRegex string   `json:"injection-regex"`
+		} `json:"grammars"`
+		Metadata struct {
+			Version string `json:"version"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Grammars) != 1 {
+		t.Fatalf("tree-sitter.json has %d grammars, want 1", len(config.Grammars))
+	}
+	grammar := config.Grammars[0]
+	want := tree_sitter_cabin.LanguageMetadata{
+		Name:           grammar.Name,
+		Scope:          grammar.Scope,
+		FileTypes:      grammar.FileTypes,
+		InjectionRegex: grammar.InjectionRegex,
+		Version:        config.Metadata.Version,
+	}
+	if got := tree_sitter_cabin.Metadata(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Metadata() = %+v, want %+v from tree-sitter.json", got, want)
+	}
+
+	// The version is also generated into the parser.
+	version := tree_sitter.NewLanguage(tree_sitter_cabin.Language()).Metadata()
+	if version == nil || fmt.Sprintf("%d.%d.%d", version.MajorVersion, version.MinorVersion, version.PatchVersion) != want.Version {
+		t.Errorf("the parser has version %+v, want %s", version, want.Version)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, test := range []struct {
+		path, firstLine string
+		want            bool
+	}{
+		{"main.cabin", "", true},
+		{"/src/lib/main.cabin", "let x = 1;", true},
+		{"main.cabin.txt", "", false},
+		{"main.go", "package main", false},
+		{"script", "#!/usr/bin/env cabin", true},
+		{"script", "#!/usr/bin/env -S cabin run", true},
+		{"script", "#!/usr/local/bin/cabin", true},
+		{"script", "#!/bin/sh", false},
+		{"script", "# cabin", false},
+		{"cabin", "", false},
+	} {
+		if got := tree_sitter_cabin.Detect(test.path, test.firstLine); got != test.want {
+			t.Errorf("Detect(%q, %q) = %v, want %v", test.path, test.firstLine, got, test.want)
+		}
+	}
+}
+
+func TestCheckABI(t *testing.T) {
+	if err := tree_sitter_cabin.CheckABI(); err != nil {
+		t.Error(err)
+	}
+}
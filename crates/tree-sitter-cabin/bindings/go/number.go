@@ -0,0 +1,83 @@
+package tree_sitter_cabin
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// ErrFraction is the error of a NumberError for a number with a fractional
+// part decoded as an integer.
+var ErrFraction = errors.New("has a fractional part")
+
+// A NumberError is a number literal that can't be decoded into the type
+// asked for. Err is strconv.ErrRange or ErrFraction.
+type NumberError struct {
+	Text string
+	Err  error
+}
+
+func (e *NumberError) Error() string {
+	if e.Err == strconv.ErrRange {
+		return fmt.Sprintf("number %s is out of range", e.Text)
+	}
+	return fmt.Sprintf("number %s %s", e.Text, e.Err)
+}
+
+func (e *NumberError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeNumber returns the exact value of a number node, which may be wrapped
+// in expression and literal nodes.
+func DecodeNumber(node *tree_sitter.Node, source []byte) (*big.Rat, error) {
+	value, _, err := decodeNumber(node, source)
+	return value, err
+}
+
+func decodeNumber(node *tree_sitter.Node, source []byte) (*big.Rat, string, error) {
+	if node = Unwrap(node); node == nil || node.Kind() != "number" {
+		return nil, "", errors.New("not a number node")
+	}
+	text := node.Utf8Text(source)
+	value, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, "", fmt.Errorf("malformed number %q", text)
+	}
+	return value, text, nil
+}
+
+// NumberInt64 decodes a number node as an int64. It fails with a NumberError
+// if the number has a fractional part or doesn't fit.
+func NumberInt64(node *tree_sitter.Node, source []byte) (int64, error) {
+	value, text, err := decodeNumber(node, source)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case !value.IsInt():
+		return 0, &NumberError{Text: text, Err: ErrFraction}
+	case !value.Num().IsInt64():
+		return 0, &NumberError{Text: text, Err: strconv.ErrRange}
+	}
+	return value.Num().Int64(), nil
+}
+
+// NumberFloat64 decodes a number node as the nearest float64. It fails with a
+// NumberError if the number is too large, or too small to be told apart from
+// zero.
+func NumberFloat64(node *tree_sitter.Node, source []byte) (float64, error) {
+	value, text, err := decodeNumber(node, source)
+	if err != nil {
+		return 0, err
+	}
+	f, _ := value.Float64()
+	if math.IsInf(f, 0) || f == 0 && value.Sign() != 0 {
+		return 0, &NumberError{Text: text, Err: strconv.ErrRange}
+	}
+	return f, nil
+}
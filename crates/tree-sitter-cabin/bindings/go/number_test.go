@@ -0,0 +1,61 @@
+package tree_sitter_cabin_test
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+)
+
+func TestDecodeNumber(t *testing.T) {
+	for _, test := range []struct {
+		text    string
+		exact   string
+		integer int64
+		intErr  error
+		float   float64
+		fltErr  error
+	}{
+		{"42", "42", 42, nil, 42, nil},
+		{"-1.25", "-5/4", 0, tree_sitter_cabin.ErrFraction, -1.25, nil},
+		{"0.1", "1/10", 0, tree_sitter_cabin.ErrFraction, 0.1, nil},
+		{"9223372036854775807", "9223372036854775807", 9223372036854775807, nil, 9223372036854775807, nil},
+		{"9223372036854775808", "9223372036854775808", 0, strconv.ErrRange, 9223372036854775808, nil},
+		{"1" + strings.Repeat("0", 400), "1" + strings.Repeat("0", 400), 0, strconv.ErrRange, 0, strconv.ErrRange},
+		{"0." + strings.Repeat("0", 400) + "1", "1/1" + strings.Repeat("0", 401), 0, tree_sitter_cabin.ErrFraction, 0, strconv.ErrRange},
+	} {
+		source := []byte("let x = " + test.text + ";")
+		tree := parse(t, string(source))
+		value := tree.RootNode().Child(0).Child(0).ChildByFieldName("value")
+
+		exact, err := tree_sitter_cabin.DecodeNumber(value, source)
+		if err != nil || exact.Cmp(mustRat(t, test.exact)) != 0 {
+			t.Errorf("DecodeNumber(%s) = %v, %v, want %s", test.text, exact, err, test.exact)
+		}
+		if integer, err := tree_sitter_cabin.NumberInt64(value, source); integer != test.integer || !errors.Is(err, test.intErr) {
+			t.Errorf("NumberInt64(%.30s) = %d, %v, want %d, %v", test.text, integer, err, test.integer, test.intErr)
+		}
+		if float, err := tree_sitter_cabin.NumberFloat64(value, source); float != test.float || !errors.Is(err, test.fltErr) {
+			t.Errorf("NumberFloat64(%.30s) = %g, %v, want %g, %v", test.text, float, err, test.float, test.fltErr)
+		}
+	}
+}
+
+func TestDecodeNumberNotANumber(t *testing.T) {
+	source := []byte(`let x = "1";`)
+	value := parse(t, string(source)).RootNode().Child(0).Child(0).ChildByFieldName("value")
+	if _, err := tree_sitter_cabin.DecodeNumber(value, source); err == nil {
+		t.Error("DecodeNumber of a string succeeded")
+	}
+}
+
+func mustRat(t *testing.T, s string) *big.Rat {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("bad rational %q", s)
+	}
+	return r
+}
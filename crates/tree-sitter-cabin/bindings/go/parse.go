@@ -0,0 +1,117 @@
+package tree_sitter_cabin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A ParseCanceledError is the error of a parse stopped because its context
+// was done.
+type ParseCanceledError struct {
+	// Offset is how far into the source the parser had got, in bytes.
+	Offset uint
+	// Err is the error of the context.
+	Err error
+}
+
+func (e *ParseCanceledError) Error() string {
+	return fmt.Sprintf("parse canceled at byte %d: %v", e.Offset, e.Err)
+}
+
+func (e *ParseCanceledError) Unwrap() error {
+	return e.Err
+}
+
+// ErrNoTree is the error of a parse that returned no tree although its context
+// wasn't done.
+var ErrNoTree = errors.New("the parser returned no tree")
+
+// maxIdleParsers bounds the number of parsers ParseContext keeps for reuse.
+// Parsers own C memory, so they can't be left to a sync.Pool.
+const maxIdleParsers = 4
+
+// parsers holds the parsers ParseContext isn't using.
+var parsers struct {
+	sync.Mutex
+	idle []*tree_sitter.Parser
+}
+
+// getParser returns an idle parser, or a new one if there is none.
+func getParser() (*tree_sitter.Parser, error) {
+	parsers.Lock()
+	if n := len(parsers.idle); n > 0 {
+		parser := parsers.idle[n-1]
+		parsers.idle = parsers.idle[:n-1]
+		parsers.Unlock()
+		return parser, nil
+	}
+	parsers.Unlock()
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(Language())); err != nil {
+		parser.Close()
+		return nil, err
+	}
+	return parser, nil
+}
+
+// putParser keeps parser for reuse, or closes it if enough parsers are idle.
+func putParser(parser *tree_sitter.Parser) {
+	parsers.Lock()
+	defer parsers.Unlock()
+	if len(parsers.idle) < maxIdleParsers {
+		parsers.idle = append(parsers.idle, parser)
+		return
+	}
+	parser.Close()
+}
+
+// ParseContext parses source, stopping when ctx is done, such as when its
+// deadline passes. The parser checks ctx as it goes, so a source that takes
+// long to parse doesn't keep it busy after that. A stopped parse fails with
+// a *ParseCanceledError, which wraps the error of ctx. ParseContext reuses
+// up to a few idle parsers, so that parsing on every request doesn't create
+// a parser each time.
+//
+// The returned tree must be closed.
+func ParseContext(ctx context.Context, source []byte) (*tree_sitter.Tree, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, &ParseCanceledError{Err: err}
+	}
+	parser, err := getParser()
+	if err != nil {
+		return nil, err
+	}
+	defer putParser(parser)
+
+	var offset uint32
+	tree := parser.ParseWithOptions(func(i int, _ tree_sitter.Point) []byte {
+		if i < len(source) {
+			return source[i:]
+		}
+		return nil
+	}, nil, &tree_sitter.ParseOptions{
+		ProgressCallback: func(state tree_sitter.ParseState) bool {
+			offset = state.CurrentByteOffset
+			select {
+			case <-ctx.Done():
+				return true
+			default:
+				return false
+			}
+		},
+	})
+	if tree == nil {
+		// A canceled parser resumes where it stopped on its next parse, so
+		// it has to be reset before parsing anything else.
+		parser.Reset()
+		if err := ctx.Err(); err != nil {
+			return nil, &ParseCanceledError{Offset: uint(offset), Err: err}
+		}
+		return nil, ErrNoTree
+	}
+	return tree, nil
+}
@@ -0,0 +1,86 @@
+package tree_sitter_cabin_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+)
+
+func TestParseContext(t *testing.T) {
+	checkParseContext(t)
+}
+
+// checkParseContext checks that ParseContext parses like a parser of its
+// own, which it doesn't after resuming a canceled parse.
+func checkParseContext(t *testing.T) {
+	t.Helper()
+	source := "let x = group { a: Number };"
+	tree, err := tree_sitter_cabin.ParseContext(context.Background(), []byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tree.Close()
+	if got, want := tree.RootNode().ToSexp(), parse(t, source).RootNode().ToSexp(); got != want {
+		t.Errorf("parsed to\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tree_sitter_cabin.ParseContext(ctx, []byte("let x = 1;"))
+	var canceled *tree_sitter_cabin.ParseCanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("ParseContext with a canceled context = %v, want a ParseCanceledError", err)
+	}
+}
+
+func TestParseContextDeadline(t *testing.T) {
+	// Unclosed braces keep the parser recovering from errors to the end.
+	source := bytes.Repeat([]byte("let x = { a: f(1, [b, { c"), 200000)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := tree_sitter_cabin.ParseContext(ctx, source)
+	var canceled *tree_sitter_cabin.ParseCanceledError
+	if !errors.As(err, &canceled) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ParseContext past its deadline = %v, want a ParseCanceledError", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("canceled parse took %v", elapsed)
+	}
+	if canceled.Offset == 0 || canceled.Offset >= uint(len(source)) {
+		t.Errorf("canceled at byte %d of %d", canceled.Offset, len(source))
+	}
+
+	// The next parse starts afresh instead of resuming the canceled one.
+	checkParseContext(t)
+}
+
+func TestParseContextConcurrent(t *testing.T) {
+	// More goroutines than parsers are kept idle, so some are closed.
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				tree, err := tree_sitter_cabin.ParseContext(context.Background(), []byte("let x = 1;"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if tree.RootNode().HasError() {
+					t.Errorf("parsed to %s", tree.RootNode().ToSexp())
+				}
+				tree.Close()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,38 @@
+// Command cabinplayground serves a page to try Cabin snippets on, for a team
+// to share on its own network.
+//
+// Usage:
+//
+//	cabinplayground [-addr localhost:7475] [-timeout 2s] [-max-bytes 65536]
+//
+// The limits apply to each request the page makes, so that a snippet can't
+// keep the server busy.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/playground"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/service"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:7475", "address to listen on")
+	timeout := flag.Duration("timeout", 2*time.Second, "how long a request may run")
+	maxBytes := flag.Int64("max-bytes", 64<<10, "largest request body to accept")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: cabinplayground [-addr addr] [-timeout duration] [-max-bytes n]")
+		os.Exit(2)
+	}
+
+	s := service.New(service.Options{Timeout: *timeout, MaxRequestBytes: *maxBytes})
+	if err := http.ListenAndServe(*addr, playground.New(s)); err != nil {
+		fmt.Fprintln(os.Stderr, "cabinplayground:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,27 @@
+// Package playground serves a page to try Cabin snippets on. The page shows
+// the highlighted source, its syntax tree and its diagnostics, which it gets
+// from the analysis service, and keeps the source in the URL so that a
+// snippet can be shared by its link.
+package playground
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/service"
+)
+
+//go:embed index.html
+var page []byte
+
+// New returns a handler that serves the page at / and the API of s under
+// /v1/, whose limits bound the requests of the page.
+func New(s *service.Service) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page)
+	})
+	mux.Handle("/v1/", s)
+	return mux
+}
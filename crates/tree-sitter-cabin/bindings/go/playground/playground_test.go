@@ -0,0 +1,48 @@
+package playground_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/playground"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/service"
+)
+
+func TestPlayground(t *testing.T) {
+	h := playground.New(service.New(service.Options{}))
+
+	recorder := httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if recorder.Code != http.StatusOK || !strings.Contains(recorder.Body.String(), "/v1/batch") {
+		t.Fatalf("GET /: status %d", recorder.Code)
+	}
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("GET /missing: status %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+
+	// The page makes one batch request for everything it shows.
+	body := `[
+		{"method": "highlight", "params": {"source": "let y = x -1;"}},
+		{"method": "lint", "params": {"source": "let y = x -1;"}},
+		{"method": "parse", "params": {"source": "let y = x -1;"}}
+	]`
+	recorder = httptest.NewRecorder()
+	h.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/v1/batch", strings.NewReader(body)))
+	var replies []struct {
+		Result map[string]json.RawMessage
+		Error  *service.Error
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &replies); err != nil || len(replies) != 3 {
+		t.Fatalf("POST /v1/batch: %v: %s", err, recorder.Body)
+	}
+	for i, key := range []string{"highlights", "diagnostics", "tree"} {
+		if replies[i].Error != nil || len(replies[i].Result[key]) == 0 {
+			t.Errorf("reply %d has no %s: %+v", i, key, replies[i])
+		}
+	}
+}
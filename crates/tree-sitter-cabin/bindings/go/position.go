@@ -0,0 +1,97 @@
+package tree_sitter_cabin
+
+import tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+// Wrappers are the node kinds that the grammar wraps around other nodes
+// without adding meaning of their own, such as the expression and literal
+// around every identifier.
+var Wrappers = map[string]bool{
+	"expression": true,
+	"literal":    true,
+	"postfix":    true,
+	"statement":  true,
+}
+
+// Unwrap descends from node through wrapper nodes that have a single named
+// child, returning the first node that isn't one.
+func Unwrap(node *tree_sitter.Node) *tree_sitter.Node {
+	for node != nil && Wrappers[node.Kind()] && node.NamedChildCount() == 1 {
+		node = node.NamedChild(0)
+	}
+	return node
+}
+
+// NodeAt returns the innermost meaningful named node at pos: wrappers are
+// unwrapped, and the pascal_case_identifier or other_identifier inside an
+// identifier resolves to the identifier.
+func NodeAt(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	node := root.NamedDescendantForPointRange(pos, pos)
+	if node == nil {
+		return nil
+	}
+	if kind := node.Kind(); kind == "pascal_case_identifier" || kind == "other_identifier" {
+		if parent := node.Parent(); parent != nil && parent.Kind() == "identifier" {
+			return parent
+		}
+	}
+	return Unwrap(node)
+}
+
+// IdentifierAt returns the identifier at pos, or nil if there isn't one. A
+// position just after the end of an identifier, where an editor's cursor is
+// while typing it, also counts.
+func IdentifierAt(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	if node := NodeAt(root, pos); node != nil && node.Kind() == "identifier" {
+		return node
+	}
+	if pos.Column == 0 {
+		return nil
+	}
+	before := tree_sitter.NewPoint(pos.Row, pos.Column-1)
+	if node := NodeAt(root, before); node != nil && node.Kind() == "identifier" && node.EndPosition() == pos {
+		return node
+	}
+	return nil
+}
+
+// EnclosingDeclaration returns the innermost declaration containing pos.
+func EnclosingDeclaration(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	return enclosing(root, pos, func(node *tree_sitter.Node) bool {
+		return node.Kind() == "declaration"
+	})
+}
+
+// EnclosingAction returns the innermost action literal containing pos.
+func EnclosingAction(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	return enclosing(root, pos, func(node *tree_sitter.Node) bool {
+		return node.Kind() == "function"
+	})
+}
+
+// EnclosingCall returns the innermost function call whose arguments contain
+// pos, which is the call a signature help request at pos is about. Calls
+// whose callee contains pos are skipped.
+func EnclosingCall(root *tree_sitter.Node, pos tree_sitter.Point) *tree_sitter.Node {
+	return enclosing(root, pos, func(node *tree_sitter.Node) bool {
+		if node.Kind() != "function_call" {
+			return false
+		}
+		callee := node.ChildByFieldName("callee")
+		return callee == nil || !before(pos, callee.EndPosition())
+	})
+}
+
+// enclosing returns the innermost ancestor-or-self of the node at pos that
+// satisfies match.
+func enclosing(root *tree_sitter.Node, pos tree_sitter.Point, match func(*tree_sitter.Node) bool) *tree_sitter.Node {
+	for node := root.NamedDescendantForPointRange(pos, pos); node != nil; node = node.Parent() {
+		if match(node) {
+			return node
+		}
+	}
+	return nil
+}
+
+func before(a, b tree_sitter.Point) bool {
+	return a.Row < b.Row || a.Row == b.Row && a.Column < b.Column
+}
@@ -0,0 +1,80 @@
+package tree_sitter_cabin_test
+
+import (
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const positionSource = "let greet = action {\n\tsystem.terminal.print(format(name, 1));\n};\n"
+
+func TestNodeAt(t *testing.T) {
+	tree := parse(t, positionSource)
+	root := tree.RootNode()
+	source := []byte(positionSource)
+
+	for _, test := range []struct {
+		row, column uint
+		kind, text  string
+	}{
+		{0, 5, "identifier", "greet"},
+		{1, 36, "number", "1"},
+		{1, 2, "identifier", "system"},
+	} {
+		node := tree_sitter_cabin.NodeAt(root, tree_sitter.NewPoint(test.row, test.column))
+		if node == nil || node.Kind() != test.kind || node.Utf8Text(source) != test.text {
+			t.Errorf("NodeAt(%d, %d) = %v, want %s %q", test.row, test.column, node, test.kind, test.text)
+		}
+	}
+}
+
+func TestIdentifierAt(t *testing.T) {
+	tree := parse(t, positionSource)
+	root := tree.RootNode()
+	source := []byte(positionSource)
+
+	for column, want := range map[uint]string{1: "system", 7: "system", 30: "name", 34: "name", 35: ""} {
+		node := tree_sitter_cabin.IdentifierAt(root, tree_sitter.NewPoint(1, column))
+		got := ""
+		if node != nil {
+			got = node.Utf8Text(source)
+		}
+		if got != want {
+			t.Errorf("IdentifierAt(1, %d) = %q, want %q", column, got, want)
+		}
+	}
+}
+
+func TestEnclosing(t *testing.T) {
+	tree := parse(t, positionSource)
+	root := tree.RootNode()
+	source := []byte(positionSource)
+
+	inName := tree_sitter.NewPoint(1, 31)
+	if node := tree_sitter_cabin.EnclosingDeclaration(root, inName); node == nil || node.ChildByFieldName("name").Utf8Text(source) != "greet" {
+		t.Errorf("EnclosingDeclaration = %v, want the declaration of greet", node)
+	}
+	if node := tree_sitter_cabin.EnclosingAction(root, inName); node == nil || node.Kind() != "function" {
+		t.Errorf("EnclosingAction = %v, want the action", node)
+	}
+
+	for column, want := range map[uint]string{
+		31: "format",
+		24: "system.terminal.print",
+		18: "",
+	} {
+		node := tree_sitter_cabin.EnclosingCall(root, tree_sitter.NewPoint(1, column))
+		got := ""
+		if node != nil {
+			got = node.ChildByFieldName("callee").Utf8Text(source)
+		}
+		if got != want {
+			t.Errorf("EnclosingCall(1, %d) has callee %q, want %q", column, got, want)
+		}
+	}
+
+	if node := tree_sitter_cabin.EnclosingAction(root, tree_sitter.NewPoint(0, 2)); node != nil {
+		t.Errorf("EnclosingAction outside of an action = %v, want nil", node)
+	}
+}
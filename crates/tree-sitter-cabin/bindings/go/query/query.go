@@ -0,0 +1,319 @@
+// Package query compiles tree-sitter query files for Cabin once per process
+// and shares them between goroutines.
+//
+// A compiled tree-sitter query is immutable and can be used concurrently, but
+// a QueryCursor can't. A Query therefore keeps a pool of cursors and hands one
+// to each caller.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/queries"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// maxIdleCursors bounds the number of cursors a Query keeps for reuse.
+// Cursors own C memory, so they can't be left to a sync.Pool.
+const maxIdleCursors = 16
+
+// Default is the registry of the query files shipped with the grammar.
+var Default = NewRegistry(queries.FS)
+
+// Highlights returns the compiled highlights.scm from Default.
+func Highlights() (*Query, error) {
+	return Default.Get("highlights.scm")
+}
+
+// An Error is a query compile error, positioned in the file it occurred in.
+// Line and Column are 1-based.
+type Error struct {
+	File    string
+	Line    int
+	Column  int
+	Kind    tree_sitter.QueryErrorKind
+	Message string
+}
+
+var errorKinds = map[tree_sitter.QueryErrorKind]string{
+	tree_sitter.QueryErrorSyntax:    "invalid syntax",
+	tree_sitter.QueryErrorNodeType:  "invalid node type",
+	tree_sitter.QueryErrorField:     "invalid field name",
+	tree_sitter.QueryErrorCapture:   "invalid capture name",
+	tree_sitter.QueryErrorPredicate: "invalid predicate",
+	tree_sitter.QueryErrorStructure: "impossible pattern",
+	tree_sitter.QueryErrorLanguage:  "incompatible language",
+}
+
+func (e *Error) Error() string {
+	kind := errorKinds[e.Kind]
+	message := strings.TrimSpace(e.Message)
+	if message == "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, kind)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s: %s", e.File, e.Line, e.Column, kind, message)
+}
+
+// A Registry compiles compositions of query files on first use and caches
+// them for the life of the process. It is safe for concurrent use.
+type Registry struct {
+	sources  []fs.FS
+	language *tree_sitter.Language
+
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	once  sync.Once
+	query *Query
+	err   error
+}
+
+// NewRegistry returns a registry that reads query files from sources. A file
+// name is looked up in each source in turn, and the first one containing it
+// is used. A project can add its own files, or override the shipped ones, by
+// passing its directory before queries.FS.
+func NewRegistry(sources ...fs.FS) *Registry {
+	return &Registry{
+		sources:  sources,
+		language: tree_sitter.NewLanguage(tree_sitter_cabin.Language()),
+		entries:  map[string]*entry{},
+	}
+}
+
+// Get returns the query made of the given files, concatenated in order. It
+// is compiled on the first call for that list of files; later calls, and
+// concurrent calls while it compiles, share the result.
+func (r *Registry) Get(files ...string) (*Query, error) {
+	if len(files) == 0 {
+		return nil, errors.New("query: no query files given")
+	}
+	key := strings.Join(files, "\x00")
+
+	r.mu.Lock()
+	e, ok := r.entries[key]
+	if !ok {
+		e = &entry{}
+		r.entries[key] = e
+	}
+	r.mu.Unlock()
+
+	e.once.Do(func() { e.query, e.err = r.compile(files) })
+	return e.query, e.err
+}
+
+func (r *Registry) read(name string) ([]byte, error) {
+	for _, source := range r.sources {
+		text, err := fs.ReadFile(source, name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return text, err
+		}
+	}
+	return nil, fmt.Errorf("query: %s: %w", name, fs.ErrNotExist)
+}
+
+func (r *Registry) compile(files []string) (*Query, error) {
+	var source strings.Builder
+	starts := make([]int, len(files))
+	for i, name := range files {
+		text, err := r.read(name)
+		if err != nil {
+			return nil, err
+		}
+		starts[i] = source.Len()
+		source.Write(text)
+		source.WriteByte('\n')
+	}
+
+	compiled, queryErr := tree_sitter.NewQuery(r.language, source.String())
+	if queryErr != nil {
+		return nil, locate(queryErr, files, starts, source.String())
+	}
+	return &Query{query: compiled, Files: slices.Clone(files)}, nil
+}
+
+// locate converts the position of an error in the concatenated source to a
+// position in the file it came from.
+func locate(queryErr *tree_sitter.QueryError, files []string, starts []int, source string) *Error {
+	offset := min(int(queryErr.Offset), len(source))
+	file := 0
+	for file+1 < len(starts) && starts[file+1] <= offset {
+		file++
+	}
+	before := source[starts[file]:offset]
+	line := strings.Count(before, "\n") + 1
+	column := len(before) - strings.LastIndexByte(before, '\n')
+	return &Error{File: files[file], Line: line, Column: column, Kind: queryErr.Kind, Message: queryErr.Message}
+}
+
+// A Query is a compiled composition of query files. Its methods are safe for
+// concurrent use. A Query lives as long as its registry, so it has only the
+// methods of a tree-sitter query that don't change or close it.
+type Query struct {
+	query *tree_sitter.Query
+	Files []string
+
+	mu      sync.Mutex
+	cursors []*tree_sitter.QueryCursor
+}
+
+// StartByteForPattern returns the offset where the pattern at index starts in
+// the concatenated query files.
+func (q *Query) StartByteForPattern(index uint) uint {
+	return q.query.StartByteForPattern(index)
+}
+
+// EndByteForPattern returns the offset where the pattern at index ends in the
+// concatenated query files.
+func (q *Query) EndByteForPattern(index uint) uint {
+	return q.query.EndByteForPattern(index)
+}
+
+// PatternCount returns the number of patterns in the query.
+func (q *Query) PatternCount() uint {
+	return q.query.PatternCount()
+}
+
+// CaptureNames returns the names of the captures, in the order of their
+// indices.
+func (q *Query) CaptureNames() []string {
+	return q.query.CaptureNames()
+}
+
+// CaptureQuantifiers returns the quantifiers of the captures in the pattern at
+// index.
+func (q *Query) CaptureQuantifiers(index uint) []tree_sitter.CaptureQuantifier {
+	return q.query.CaptureQuantifiers(index)
+}
+
+// CaptureIndexForName returns the index of the capture called name.
+func (q *Query) CaptureIndexForName(name string) (uint, bool) {
+	return q.query.CaptureIndexForName(name)
+}
+
+// PropertyPredicates returns the #is? and #is-not? predicates of the pattern
+// at index.
+func (q *Query) PropertyPredicates(index uint) []tree_sitter.PropertyPredicate {
+	return q.query.PropertyPredicates(index)
+}
+
+// PropertySettings returns the #set! properties of the pattern at index.
+func (q *Query) PropertySettings(index uint) []tree_sitter.QueryProperty {
+	return q.query.PropertySettings(index)
+}
+
+// GeneralPredicates returns the predicates of the pattern at index other than
+// #match?, #eq?, #is? and #set! and their negations.
+func (q *Query) GeneralPredicates(index uint) []tree_sitter.QueryPredicate {
+	return q.query.GeneralPredicates(index)
+}
+
+// IsPatternRooted reports whether the pattern at index has a single root node.
+func (q *Query) IsPatternRooted(index uint) bool {
+	return q.query.IsPatternRooted(index)
+}
+
+// IsPatternNonLocal reports whether the pattern at index has several root
+// nodes and can match within a repeating sequence of siblings.
+func (q *Query) IsPatternNonLocal(index uint) bool {
+	return q.query.IsPatternNonLocal(index)
+}
+
+// IsPatternGuaranteedAtStep reports whether the pattern with a step at
+// byteOffset is sure to match once it reaches the step.
+func (q *Query) IsPatternGuaranteedAtStep(byteOffset uint) bool {
+	return q.query.IsPatternGuaranteedAtStep(byteOffset)
+}
+
+// Cursor returns a cursor for the calling goroutine's exclusive use. Run the
+// query with it through MatchesWith or CapturesWith, and give it back with
+// Release when done.
+func (q *Query) Cursor() *tree_sitter.QueryCursor {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if n := len(q.cursors); n > 0 {
+		cursor := q.cursors[n-1]
+		q.cursors = q.cursors[:n-1]
+		return cursor
+	}
+	return tree_sitter.NewQueryCursor()
+}
+
+// defaultMatchLimit is the match limit of a new cursor.
+var defaultMatchLimit = sync.OnceValue(func() uint {
+	cursor := tree_sitter.NewQueryCursor()
+	defer cursor.Close()
+	return cursor.MatchLimit()
+})
+
+// Release returns a cursor obtained from Cursor. The cursor must not be used
+// afterwards. Its ranges and limits are reset, so that the next caller gets
+// a cursor that finds every match.
+func (q *Query) Release(cursor *tree_sitter.QueryCursor) {
+	cursor.SetByteRange(0, math.MaxUint32)
+	cursor.SetPointRange(tree_sitter.Point{}, tree_sitter.Point{Row: math.MaxUint32, Column: math.MaxUint32})
+	cursor.SetMaxStartDepth(nil)
+	cursor.SetMatchLimit(defaultMatchLimit())
+	cursor.SetTimeoutMicros(0)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.cursors) < maxIdleCursors {
+		q.cursors = append(q.cursors, cursor)
+		return
+	}
+	cursor.Close()
+}
+
+// Matches returns an iterator over the matches of the query in node, using a
+// pooled cursor that is released when the loop ends. The match is only valid
+// until the next iteration.
+func (q *Query) Matches(node *tree_sitter.Node, source []byte) iter.Seq[*tree_sitter.QueryMatch] {
+	return func(yield func(*tree_sitter.QueryMatch) bool) {
+		cursor := q.Cursor()
+		defer q.Release(cursor)
+
+		matches := q.MatchesWith(cursor, node, source)
+		for match := matches.Next(); match != nil; match = matches.Next() {
+			if !yield(match) {
+				return
+			}
+		}
+	}
+}
+
+// MatchesWith runs the query in node with a cursor obtained from Cursor, whose
+// ranges and limits the caller may have set.
+func (q *Query) MatchesWith(cursor *tree_sitter.QueryCursor, node *tree_sitter.Node, source []byte) tree_sitter.QueryMatches {
+	return cursor.Matches(q.query, node, source)
+}
+
+// CapturesWith runs the query in node with a cursor obtained from Cursor, like
+// MatchesWith, and returns its captures in order.
+func (q *Query) CapturesWith(cursor *tree_sitter.QueryCursor, node *tree_sitter.Node, source []byte) tree_sitter.QueryCaptures {
+	return cursor.Captures(q.query, node, source)
+}
+
+// Captures returns an iterator over the captures of the query in node, like
+// tree_sitter_cabin.Captures, using a pooled cursor.
+func (q *Query) Captures(node *tree_sitter.Node, source []byte) iter.Seq2[*tree_sitter.QueryMatch, uint] {
+	return func(yield func(*tree_sitter.QueryMatch, uint) bool) {
+		cursor := q.Cursor()
+		defer q.Release(cursor)
+
+		captures := q.CapturesWith(cursor, node, source)
+		for match, index := captures.Next(); match != nil; match, index = captures.Next() {
+			if !yield(match, index) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package query_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/query"
+	"github.com/language-cabin/tree-sitter-cabin/queries"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+func TestHighlightsCompileOnce(t *testing.T) {
+	first, err := query.Highlights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := query.Highlights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("highlights.scm was compiled twice")
+	}
+}
+
+func TestConcurrentMatches(t *testing.T) {
+	highlights, err := query.Highlights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := []byte("let greet = action { system.terminal.print(\"hi\"); };")
+
+	var wg sync.WaitGroup
+	counts := make([]int, 8)
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			parser := tree_sitter.NewParser()
+			defer parser.Close()
+			parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language()))
+			tree := parser.Parse(source, nil)
+			defer tree.Close()
+			for range highlights.Captures(tree.RootNode(), source) {
+				counts[i]++
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, count := range counts {
+		if count == 0 || count != counts[0] {
+			t.Fatalf("goroutines saw different captures: %v", counts)
+		}
+	}
+}
+
+func TestCompose(t *testing.T) {
+	project := fstest.MapFS{
+		"custom.scm": {Data: []byte("(match) @conditional\n")},
+		"broken.scm": {Data: []byte("; a comment\n(declaration\n  nmae: (identifier))\n")},
+	}
+	registry := query.NewRegistry(queries.FS, project)
+
+	composed, err := registry.Get("highlights.scm", "custom.scm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := composed.CaptureIndexForName("conditional"); !ok {
+		t.Error("composed query is missing the custom capture")
+	}
+
+	// The registry keeps its own list of the files.
+	files := []string{"custom.scm"}
+	custom, err := registry.Get(files...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files[0] = "changed.scm"
+	if custom.Files[0] != "custom.scm" {
+		t.Errorf("the files of the query changed to %v", custom.Files)
+	}
+
+	_, err = registry.Get("custom.scm", "broken.scm")
+	var queryErr *query.Error
+	if !errors.As(err, &queryErr) {
+		t.Fatalf("expected a *query.Error, got %v", err)
+	}
+	if queryErr.File != "broken.scm" || queryErr.Line != 3 || queryErr.Column != 3 || queryErr.Kind != tree_sitter.QueryErrorField {
+		t.Errorf("got error %v", queryErr)
+	}
+	if _, again := registry.Get("custom.scm", "broken.scm"); again != err {
+		t.Error("the compile error wasn't cached")
+	}
+
+	if _, err := registry.Get("missing.scm"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestReleaseResetsCursor(t *testing.T) {
+	highlights, err := query.Highlights()
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := []byte("let greet = action {\n\tsystem.terminal.print(\"hi\");\n};")
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language()))
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	count := func() int {
+		n := 0
+		for range highlights.Captures(tree.RootNode(), source) {
+			n++
+		}
+		return n
+	}
+	want := count()
+
+	// The next caller gets the same cursor back, and must not see the
+	// settings of this one.
+	cursor := highlights.Cursor()
+	depth := uint(0)
+	cursor.SetPointRange(tree_sitter.Point{}, tree_sitter.Point{Row: 1})
+	cursor.SetByteRange(0, 4)
+	cursor.SetMaxStartDepth(&depth)
+	cursor.SetMatchLimit(1)
+	restricted := 0
+	captures := highlights.CapturesWith(cursor, tree.RootNode(), source)
+	for match, _ := captures.Next(); match != nil; match, _ = captures.Next() {
+		restricted++
+	}
+	if restricted >= want {
+		t.Errorf("got %d captures with a restricted cursor, want fewer than %d", restricted, want)
+	}
+	highlights.Release(cursor)
+	if got := count(); got != want {
+		t.Errorf("got %d captures after releasing a restricted cursor, want %d", got, want)
+	}
+}
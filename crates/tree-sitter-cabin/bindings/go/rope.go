@@ -0,0 +1,207 @@
+package tree_sitter_cabin
+
+import "bytes"
+
+// maxLeaf is the most bytes a rope keeps in one leaf.
+const maxLeaf = 2048
+
+// A rope is an immutable text, kept as a height-balanced binary tree of
+// byte slices. A nil rope is empty. Each node knows the length and the number
+// of line breaks of its text, so that offsets and lines are found in
+// logarithmic time.
+type rope struct {
+	left, right *rope
+	leaf        []byte
+	length      int
+	newlines    int
+	height      int
+}
+
+func newLeaf(text []byte) *rope {
+	if len(text) == 0 {
+		return nil
+	}
+	return &rope{leaf: text, length: len(text), newlines: bytes.Count(text, []byte{'\n'})}
+}
+
+// buildRope returns a balanced rope of a copy of text.
+func buildRope(text []byte) *rope {
+	if len(text) <= maxLeaf {
+		return newLeaf(bytes.Clone(text))
+	}
+	// Split in a whole number of leaves, so that all of them are full.
+	middle := (len(text)/maxLeaf + 1) / 2 * maxLeaf
+	return node(buildRope(text[:middle]), buildRope(text[middle:]))
+}
+
+func node(left, right *rope) *rope {
+	return &rope{
+		left:     left,
+		right:    right,
+		length:   left.length + right.length,
+		newlines: left.newlines + right.newlines,
+		height:   max(left.height, right.height) + 1,
+	}
+}
+
+func (r *rope) len() int {
+	if r == nil {
+		return 0
+	}
+	return r.length
+}
+
+// join returns the concatenation of a and b. The leaves on either side of the
+// seam are merged if they fit in one, so that small edits such as typing
+// don't leave the rope in ever smaller leaves.
+func join(a, b *rope) *rope {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.leaf != nil && b.leaf != nil && a.length+b.length <= maxLeaf:
+		return newLeaf(append(append(make([]byte, 0, a.length+b.length), a.leaf...), b.leaf...))
+	}
+	if last, first := a.lastLeaf(), b.firstLeaf(); len(last)+len(first) <= maxLeaf {
+		rest, _ := split(a, a.length-len(last))
+		_, after := split(b, len(first))
+		merged := append(append(make([]byte, 0, len(last)+len(first)), last...), first...)
+		return join(join(rest, newLeaf(merged)), after)
+	}
+	switch {
+	case a.height > b.height+1:
+		return balanced(a.left, join(a.right, b))
+	case b.height > a.height+1:
+		return balanced(join(a, b.left), b.right)
+	}
+	return node(a, b)
+}
+
+// balanced returns the concatenation of two ropes whose heights differ by at
+// most 2, rotating them if they differ by 2.
+func balanced(left, right *rope) *rope {
+	switch {
+	case left.height > right.height+1:
+		if left.left.height >= left.right.height {
+			return node(left.left, node(left.right, right))
+		}
+		return node(node(left.left, left.right.left), node(left.right.right, right))
+	case right.height > left.height+1:
+		if right.right.height >= right.left.height {
+			return node(node(left, right.left), right.right)
+		}
+		return node(node(left, right.left.left), node(right.left.right, right.right))
+	}
+	return node(left, right)
+}
+
+// split returns the text of r before and after offset.
+func split(r *rope, offset int) (*rope, *rope) {
+	switch {
+	case offset <= 0:
+		return nil, r
+	case offset >= r.len():
+		return r, nil
+	case r.leaf != nil:
+		return newLeaf(r.leaf[:offset]), newLeaf(r.leaf[offset:])
+	case offset < r.left.length:
+		left, right := split(r.left, offset)
+		return left, join(right, r.right)
+	}
+	left, right := split(r.right, offset-r.left.length)
+	return join(r.left, left), right
+}
+
+// firstLeaf returns the text of the first leaf of r.
+func (r *rope) firstLeaf() []byte {
+	for r.leaf == nil {
+		r = r.left
+	}
+	return r.leaf
+}
+
+// lastLeaf returns the text of the last leaf of r.
+func (r *rope) lastLeaf() []byte {
+	for r.leaf == nil {
+		r = r.right
+	}
+	return r.leaf
+}
+
+// chunk returns the text of r from offset to the end of its leaf.
+func (r *rope) chunk(offset int) []byte {
+	if offset >= r.len() {
+		return nil
+	}
+	for r.leaf == nil {
+		if offset < r.left.length {
+			r = r.left
+		} else {
+			offset -= r.left.length
+			r = r.right
+		}
+	}
+	return r.leaf[offset:]
+}
+
+// appendTo appends the text of r from start to end to dst.
+func (r *rope) appendTo(dst []byte, start, end int) []byte {
+	if r == nil || start >= end {
+		return dst
+	}
+	if r.leaf != nil {
+		return append(dst, r.leaf[start:end]...)
+	}
+	if start < r.left.length {
+		dst = r.left.appendTo(dst, start, min(end, r.left.length))
+	}
+	if end > r.left.length {
+		dst = r.right.appendTo(dst, max(start-r.left.length, 0), end-r.left.length)
+	}
+	return dst
+}
+
+// newlinesBefore returns the number of line breaks in r before offset.
+func (r *rope) newlinesBefore(offset int) int {
+	if r == nil {
+		return 0
+	}
+	n := 0
+	for r.leaf == nil {
+		if offset < r.left.length {
+			r = r.left
+		} else {
+			n += r.left.newlines
+			offset -= r.left.length
+			r = r.right
+		}
+	}
+	return n + bytes.Count(r.leaf[:offset], []byte{'\n'})
+}
+
+// lineStart returns the offset of the start of line row, counting from 0. It
+// returns -1 if r has fewer lines.
+func (r *rope) lineStart(row int) int {
+	switch {
+	case row == 0:
+		return 0
+	case r == nil || row > r.newlines:
+		return -1
+	}
+	offset := 0
+	for r.leaf == nil {
+		if row <= r.left.newlines {
+			r = r.left
+		} else {
+			row -= r.left.newlines
+			offset += r.left.length
+			r = r.right
+		}
+	}
+	i := -1
+	for ; row > 0; row-- {
+		i += bytes.IndexByte(r.leaf[i+1:], '\n') + 1
+	}
+	return offset + i + 1
+}
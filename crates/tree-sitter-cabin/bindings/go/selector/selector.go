@@ -0,0 +1,565 @@
+// Package selector implements a CSS-like selector language over Cabin syntax
+// trees, as a friendlier alternative to tree-sitter's S-expression queries.
+//
+// A selector is a comma-separated list of alternatives. Each alternative is a
+// chain of compound selectors joined by combinators:
+//
+//	a b        b is a descendant of a
+//	a > b      b is a child of a
+//
+// A compound selector starts with a name or *. A name matches named nodes of
+// that kind and named nodes in a field of that name, so both
+// "declaration > type" and "function_call > callee" read naturally. It can be
+// followed by any number of conditions:
+//
+//	[field]              the node has a child in field
+//	[field = "text"]     the text of that child is exactly text
+//	[field ~ "text"]     the text of that child contains text
+//	[field = /regexp/]   the text of that child matches regexp (~ works too)
+//	= "text", ~ "text"   the same tests against the text of the node itself
+//	:has(selector)       a descendant matches selector; start it with > to
+//	                     only consider children
+//	:not(selector)       the node doesn't match any compound in selector
+//
+// For example, "declaration[name = /^Test/] > type" selects the type
+// annotations of declarations whose name starts with Test, and
+// `function_call:has(> callee ~ "terminal.print")` selects calls to
+// terminal.print.
+//
+// Selectors compile to tree walks; they don't go through tree-sitter queries.
+package selector
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"strings"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/walk"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A Selector is a compiled selector. It is safe for concurrent use.
+type Selector struct {
+	source       string
+	alternatives []chain
+}
+
+type combinator int
+
+const (
+	descendant combinator = iota
+	child
+)
+
+// A chain is one alternative of a selector. combinators[i] joins
+// compounds[i-1] and compounds[i]; combinators[0] is the leading combinator
+// of a relative selector inside :has.
+type chain struct {
+	compounds   []compound
+	combinators []combinator
+}
+
+type compound struct {
+	name       string // "" matches any named node
+	conditions []condition
+}
+
+type condition interface {
+	matches(f frame, source []byte) bool
+}
+
+// A frame is a named node on the path from the root of a walk, with the name
+// of the field it is in.
+type frame struct {
+	node  tree_sitter.Node
+	field string
+}
+
+// A SyntaxError reports an invalid selector.
+type SyntaxError struct {
+	Selector string
+	Offset   int
+	Message  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("selector %q: column %d: %s", e.Selector, e.Offset+1, e.Message)
+}
+
+// Compile parses a selector.
+func Compile(source string) (*Selector, error) {
+	p := &parser{source: source}
+	p.skipSpace()
+	alternatives, err := p.list(false)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(source) {
+		return nil, p.errorf("unexpected %q", source[p.pos:p.pos+1])
+	}
+	return &Selector{source: source, alternatives: alternatives}, nil
+}
+
+// MustCompile is like Compile but panics if the selector is invalid.
+func MustCompile(source string) *Selector {
+	s, err := Compile(source)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// String returns the source of the selector.
+func (s *Selector) String() string {
+	return s.source
+}
+
+// All returns an iterator over the nodes under root, root included, that
+// match the selector, in depth-first order.
+func (s *Selector) All(root *tree_sitter.Node, source []byte) iter.Seq[*tree_sitter.Node] {
+	return func(yield func(*tree_sitter.Node) bool) {
+		walkFrames(root, func(frames []frame) walk.Action {
+			for _, alternative := range s.alternatives {
+				if alternative.matches(frames, -1, source) {
+					node := frames[len(frames)-1].node
+					if !yield(&node) {
+						return walk.Stop
+					}
+					break
+				}
+			}
+			return walk.Continue
+		})
+	}
+}
+
+// Match reports whether node matches the selector, looking at its ancestors
+// for the combinators.
+func (s *Selector) Match(node *tree_sitter.Node, source []byte) bool {
+	var frames []frame
+	for current := node; current != nil; current = current.Parent() {
+		if current.IsNamed() {
+			frames = append(frames, frame{node: *current, field: fieldName(current)})
+		}
+	}
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	if len(frames) == 0 || !node.IsNamed() {
+		return false
+	}
+	for _, alternative := range s.alternatives {
+		if alternative.matches(frames, -1, source) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldName(node *tree_sitter.Node) string {
+	parent := node.Parent()
+	if parent == nil {
+		return ""
+	}
+	for i := uint(0); i < parent.ChildCount(); i++ {
+		if parent.Child(i).Id() == node.Id() {
+			return parent.FieldNameForChild(uint32(i))
+		}
+	}
+	return ""
+}
+
+// walkFrames calls visit with the path of named nodes from root to every
+// named node under it.
+func walkFrames(root *tree_sitter.Node, visit func([]frame) walk.Action) {
+	walk.Walk(root, &framer{visit: visit})
+}
+
+type framer struct {
+	frames []frame
+	visit  func([]frame) walk.Action
+}
+
+func (f *framer) Enter(c *walk.Cursor) walk.Action {
+	node := c.Node()
+	if !node.IsNamed() {
+		return walk.SkipChildren
+	}
+	f.frames = append(f.frames, frame{node: *node, field: c.FieldName()})
+	return f.visit(f.frames)
+}
+
+func (f *framer) Leave(c *walk.Cursor) {
+	if c.Node().IsNamed() {
+		f.frames = f.frames[:len(f.frames)-1]
+	}
+}
+
+// matches reports whether the last frame matches the chain. Frames at or
+// before anchor are outside the scope of the chain; anchor is -1 for a whole
+// tree and the index of the subject for :has.
+func (c chain) matches(frames []frame, anchor int, source []byte) bool {
+	return c.matchFrom(len(c.compounds)-1, len(frames)-1, frames, anchor, source)
+}
+
+func (c chain) matchFrom(k, i int, frames []frame, anchor int, source []byte) bool {
+	if i <= anchor || !c.compounds[k].matches(frames[i], source) {
+		return false
+	}
+	if k == 0 {
+		return anchor < 0 || c.combinators[0] == descendant || i == anchor+1
+	}
+	if c.combinators[k] == child {
+		return c.matchFrom(k-1, i-1, frames, anchor, source)
+	}
+	for j := i - 1; j > anchor; j-- {
+		if c.matchFrom(k-1, j, frames, anchor, source) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c compound) matches(f frame, source []byte) bool {
+	if c.name != "" && f.node.Kind() != c.name && f.field != c.name {
+		return false
+	}
+	for _, condition := range c.conditions {
+		if !condition.matches(f, source) {
+			return false
+		}
+	}
+	return true
+}
+
+// A textTest tests the text of a node.
+type textTest struct {
+	contains bool
+	text     string
+	pattern  *regexp.Regexp
+}
+
+func (t textTest) test(text string) bool {
+	switch {
+	case t.pattern != nil:
+		return t.pattern.MatchString(text)
+	case t.contains:
+		return strings.Contains(text, t.text)
+	}
+	return text == t.text
+}
+
+type hasField struct {
+	field string
+	test  *textTest
+}
+
+func (h hasField) matches(f frame, source []byte) bool {
+	cursor := f.node.Walk()
+	defer cursor.Close()
+	for _, child := range f.node.ChildrenByFieldName(h.field, cursor) {
+		if h.test == nil || h.test.test(child.Utf8Text(source)) {
+			return true
+		}
+	}
+	return false
+}
+
+type hasText struct {
+	test textTest
+}
+
+func (h hasText) matches(f frame, source []byte) bool {
+	return h.test.test(f.node.Utf8Text(source))
+}
+
+type has struct {
+	alternatives []chain
+}
+
+func (h has) matches(f frame, source []byte) bool {
+	found := false
+	walkFrames(&f.node, func(frames []frame) walk.Action {
+		if len(frames) == 1 {
+			return walk.Continue
+		}
+		for _, alternative := range h.alternatives {
+			if alternative.matches(frames, 0, source) {
+				found = true
+				return walk.Stop
+			}
+		}
+		return walk.Continue
+	})
+	return found
+}
+
+type not struct {
+	compounds []compound
+}
+
+func (n not) matches(f frame, source []byte) bool {
+	for _, compound := range n.compounds {
+		if compound.matches(f, source) {
+			return false
+		}
+	}
+	return true
+}
+
+type parser struct {
+	source string
+	pos    int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return &SyntaxError{Selector: p.source, Offset: p.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.source) {
+		return p.source[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) skipSpace() bool {
+	start := p.pos
+	for p.pos < len(p.source) && strings.IndexByte(" \t\r\n", p.source[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *parser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return p.errorf("expected %q", c)
+	}
+	p.pos++
+	return nil
+}
+
+// list parses comma-separated chains. Relative chains may start with a
+// combinator, as in :has(> callee).
+func (p *parser) list(relative bool) ([]chain, error) {
+	var chains []chain
+	for {
+		c, err := p.chain(relative)
+		if err != nil {
+			return nil, err
+		}
+		chains = append(chains, c)
+		p.skipSpace()
+		if p.peek() != ',' {
+			return chains, nil
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func (p *parser) chain(relative bool) (chain, error) {
+	var c chain
+	next := descendant
+	if p.peek() == '>' {
+		if !relative {
+			return chain{}, p.errorf("a selector can only start with > inside :has")
+		}
+		p.pos++
+		p.skipSpace()
+		next = child
+	}
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return chain{}, err
+		}
+		c.compounds = append(c.compounds, compound)
+		c.combinators = append(c.combinators, next)
+
+		spaced := p.skipSpace()
+		switch {
+		case p.peek() == '>':
+			p.pos++
+			p.skipSpace()
+			next = child
+		case spaced && p.startsCompound():
+			next = descendant
+		default:
+			return c, nil
+		}
+	}
+}
+
+func (p *parser) startsCompound() bool {
+	c := p.peek()
+	return c == '*' || c == '[' || c == ':' || isIdentifierStart(c)
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+func (p *parser) identifier() (string, error) {
+	start := p.pos
+	if !isIdentifierStart(p.peek()) {
+		return "", p.errorf("expected a name")
+	}
+	for p.pos < len(p.source) && isIdentifierPart(p.source[p.pos]) {
+		p.pos++
+	}
+	return p.source[start:p.pos], nil
+}
+
+func (p *parser) compound() (compound, error) {
+	var c compound
+	switch {
+	case p.peek() == '*':
+		p.pos++
+	case isIdentifierStart(p.peek()):
+		c.name, _ = p.identifier()
+	case p.peek() != '[' && p.peek() != ':':
+		return compound{}, p.errorf("expected a selector")
+	}
+
+	for {
+		switch p.peek() {
+		case '[':
+			p.pos++
+			p.skipSpace()
+			field, err := p.identifier()
+			if err != nil {
+				return compound{}, err
+			}
+			p.skipSpace()
+			h := hasField{field: field}
+			if p.peek() == '=' || p.peek() == '~' {
+				test, err := p.textTest()
+				if err != nil {
+					return compound{}, err
+				}
+				h.test = &test
+			}
+			if err := p.expect(']'); err != nil {
+				return compound{}, err
+			}
+			c.conditions = append(c.conditions, h)
+
+		case ':':
+			start := p.pos
+			p.pos++
+			name, err := p.identifier()
+			if err != nil {
+				return compound{}, err
+			}
+			if err := p.expect('('); err != nil {
+				return compound{}, err
+			}
+			p.skipSpace()
+			switch name {
+			case "has":
+				alternatives, err := p.list(true)
+				if err != nil {
+					return compound{}, err
+				}
+				c.conditions = append(c.conditions, has{alternatives})
+			case "not":
+				alternatives, err := p.list(false)
+				if err != nil {
+					return compound{}, err
+				}
+				n := not{}
+				for _, alternative := range alternatives {
+					if len(alternative.compounds) != 1 {
+						return compound{}, p.errorf(":not only takes compound selectors")
+					}
+					n.compounds = append(n.compounds, alternative.compounds[0])
+				}
+				c.conditions = append(c.conditions, n)
+			default:
+				p.pos = start
+				return compound{}, p.errorf("unknown pseudo-class :%s", name)
+			}
+			if err := p.expect(')'); err != nil {
+				return compound{}, err
+			}
+
+		default:
+			// A text test may be separated from its compound by spaces,
+			// which otherwise would be a descendant combinator.
+			start := p.pos
+			p.skipSpace()
+			if p.peek() != '=' && p.peek() != '~' {
+				p.pos = start
+				return c, nil
+			}
+			test, err := p.textTest()
+			if err != nil {
+				return compound{}, err
+			}
+			c.conditions = append(c.conditions, hasText{test})
+		}
+	}
+}
+
+// textTest parses an operator followed by a string or regular expression.
+func (p *parser) textTest() (textTest, error) {
+	test := textTest{contains: p.peek() == '~'}
+	p.pos++
+	p.skipSpace()
+
+	switch p.peek() {
+	case '"':
+		text, err := p.delimited('"')
+		if err != nil {
+			return textTest{}, err
+		}
+		test.text = text
+	case '/':
+		start := p.pos
+		text, err := p.delimited('/')
+		if err != nil {
+			return textTest{}, err
+		}
+		pattern, err := regexp.Compile(text)
+		if err != nil {
+			p.pos = start
+			return textTest{}, p.errorf("%v", err)
+		}
+		test.pattern = pattern
+	default:
+		return textTest{}, p.errorf("expected a string or a /regular expression/")
+	}
+	return test, nil
+}
+
+// delimited parses text between two delimiters, where a backslash escapes
+// the delimiter and itself. Other escapes are kept, so regular expressions
+// can use them.
+func (p *parser) delimited(delimiter byte) (string, error) {
+	start := p.pos
+	p.pos++
+	var builder strings.Builder
+	for p.pos < len(p.source) {
+		c := p.source[p.pos]
+		switch {
+		case c == delimiter:
+			p.pos++
+			return builder.String(), nil
+		case c == '\\' && p.pos+1 < len(p.source) && (p.source[p.pos+1] == delimiter || p.source[p.pos+1] == '\\' && delimiter == '"'):
+			builder.WriteByte(p.source[p.pos+1])
+			p.pos += 2
+		default:
+			builder.WriteByte(c)
+			p.pos++
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated %c", delimiter)
+}
@@ -0,0 +1,96 @@
+package selector_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/selector"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const source = `
+let TestAdd: Number = 1;
+let helper: Text = "x";
+let TestPrint = action {
+	system.terminal.print("hi");
+	helper.print("no");
+};
+`
+
+func parse(t *testing.T, source string) *tree_sitter.Tree {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	t.Cleanup(parser.Close)
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func TestAll(t *testing.T) {
+	tree := parse(t, source)
+
+	for selectorSource, want := range map[string][]string{
+		"declaration[name = /^Test/] > type":                        {"Number"},
+		"declaration > type":                                        {"Number", "Text"},
+		`function_call:has(callee ~ "terminal.print")`:              {`system.terminal.print("hi")`},
+		`function_call:has(> callee = "helper.print")`:              {`helper.print("no")`},
+		`declaration[name ~ "Test"]:not([type]) > value ~ "action"`: {"action {\n\tsystem.terminal.print(\"hi\");\n\thelper.print(\"no\");\n}"},
+		"string, number":                                            {"1", `"x"`, `"hi"`, `"no"`},
+		"block statement function_call > arguments":                 {`"hi"`, `"no"`},
+		"declaration callee":                                        {"system.terminal.print", "helper.print"},
+	} {
+		s, err := selector.Compile(selectorSource)
+		if err != nil {
+			t.Errorf("compiling %q: %v", selectorSource, err)
+			continue
+		}
+		var got []string
+		for node := range s.All(tree.RootNode(), []byte(source)) {
+			got = append(got, node.Utf8Text([]byte(source)))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q selected %q, want %q", selectorSource, got, want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tree := parse(t, source)
+	s := selector.MustCompile("declaration[name = /^Test/] > type")
+
+	matched := 0
+	for node := range tree_sitter_cabin.Descendants(tree.RootNode(), "type") {
+		if s.Match(node, []byte(source)) {
+			matched++
+		}
+	}
+	if matched != 1 {
+		t.Errorf("matched %d types, want 1", matched)
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	for selectorSource, offset := range map[string]int{
+		"declaration[name":       16,
+		"> type":                 0,
+		"a:is(b)":                1,
+		`a[name = "unterminated`: 9,
+		"a[name = /(/]":          9,
+		"a )":                    2,
+	} {
+		_, err := selector.Compile(selectorSource)
+		var syntaxErr *selector.SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected a syntax error, got %v", selectorSource, err)
+			continue
+		}
+		if syntaxErr.Offset != offset {
+			t.Errorf("%q: error at %d, want %d: %v", selectorSource, syntaxErr.Offset, offset, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Command cabinservice serves the analysis of Cabin source over HTTP, for
+// editors and other tools that aren't written in Go.
+//
+// Usage:
+//
+//	cabinservice [-addr localhost:7474] [-timeout 10s] [-project dir]
+//
+// The rules of the project in -project, if it has any, are checked along
+// with the default ones.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/service"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:7474", "address to listen on")
+	timeout := flag.Duration("timeout", service.DefaultTimeout, "how long a request may run")
+	project := flag.String("project", "", "project whose lint rules to check")
+	flag.Parse()
+	if flag.NArg() != 0 {
+		fmt.Fprintln(os.Stderr, "usage: cabinservice [-addr addr] [-timeout duration] [-project dir]")
+		os.Exit(2)
+	}
+
+	rules := service.DefaultRules
+	if *project != "" {
+		loaded, err := lint.LoadProjectRules(*project)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cabinservice:", err)
+			os.Exit(1)
+		}
+		rules = append(rules[:len(rules):len(rules)], lint.Rules(loaded)...)
+	}
+	s := service.New(service.Options{Rules: rules, Timeout: *timeout})
+	if err := http.ListenAndServe(*addr, s); err != nil {
+		fmt.Fprintln(os.Stderr, "cabinservice:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,526 @@
+// Package service serves the Go analysis of Cabin source to other programs,
+// as a long-running local HTTP service that keeps its parsers and compiled
+// rules warm between requests.
+//
+// The API is JSON, versioned by the first element of the path. Version 1 has
+// a method per kind of request, each posted to /v1/<method> with a Request:
+//
+//	parse      the syntax tree of the source
+//	lint       the diagnostics of the rules, with their fixes
+//	node       the node at a position, and the nodes that enclose it
+//	expected   the tokens the grammar allows at a position
+//	highlight  the highlights.scm captures of the source
+//
+// A list of Calls posted to /v1/batch is answered with a Reply for each, in
+// order. A request stops when its client disconnects, when it runs past the
+// timeout of the service, or when its ID is posted to /v1/cancel.
+//
+// Positions are zero-based rows and byte columns, like those of tree-sitter.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/lint"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/query"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// DefaultRules are the rules a service checks source with unless it's given
+// others.
+var DefaultRules = []lint.Rule{
+	lint.Syntax,
+	lint.Comparisons,
+	lint.NegativeLiterals,
+	lint.NumberRanges,
+	lint.Whitespace,
+}
+
+// The limits a service has unless it's given others.
+const (
+	DefaultTimeout         = 10 * time.Second
+	DefaultMaxRequestBytes = 8 << 20
+)
+
+// Options configure a service. The zero value of a field stands for its
+// default.
+type Options struct {
+	// Rules are the rules the lint method checks source with.
+	Rules []lint.Rule
+	// Timeout bounds how long a request, or each call of a batch, may run.
+	Timeout time.Duration
+	// MaxRequestBytes bounds the size of a request body.
+	MaxRequestBytes int64
+}
+
+// A Request is the parameters of a method.
+type Request struct {
+	// ID names the request, so that it can be canceled. It's optional, but
+	// no two running requests can have the same one.
+	ID string `json:"id,omitempty"`
+	// Name is the name of the file the source is from.
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source"`
+	// Position is the position the node and expected methods are about.
+	Position *Point `json:"position,omitempty"`
+}
+
+// A Point is a position in source.
+type Point struct {
+	Row    uint `json:"row"`
+	Column uint `json:"column"`
+}
+
+// A Range is a span of source.
+type Range struct {
+	Start     Point `json:"start"`
+	End       Point `json:"end"`
+	StartByte uint  `json:"startByte"`
+	EndByte   uint  `json:"endByte"`
+}
+
+// A Node is a node of a syntax tree.
+type Node struct {
+	Kind  string `json:"kind"`
+	Named bool   `json:"named"`
+	// Field is the field of the parent the node is in, if any.
+	Field string `json:"field,omitempty"`
+	Range Range  `json:"range"`
+	// Missing reports whether the parser inserted the node to recover from
+	// an error.
+	Missing  bool    `json:"missing,omitempty"`
+	Children []*Node `json:"children,omitempty"`
+}
+
+// ParseResult is the result of the parse method.
+type ParseResult struct {
+	Tree *Node `json:"tree"`
+	// Errors is the number of syntax errors in the tree.
+	Errors int `json:"errors"`
+}
+
+// LintResult is the result of the lint method.
+type LintResult struct {
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// A Diagnostic is a problem found by a rule.
+type Diagnostic struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	Range    Range  `json:"range"`
+	Fix      *Fix   `json:"fix,omitempty"`
+}
+
+// A Fix is a set of edits that resolves a diagnostic.
+type Fix struct {
+	Message string `json:"message"`
+	Edits   []Edit `json:"edits"`
+}
+
+// An Edit replaces the source in Range with Text.
+type Edit struct {
+	Range Range  `json:"range"`
+	Text  string `json:"text"`
+}
+
+// NodeResult is the result of the node method. Each field is nil if there's
+// no such node at the position.
+type NodeResult struct {
+	// Node is the innermost meaningful named node at the position.
+	Node *Summary `json:"node"`
+	// Identifier is the identifier at, or just before, the position.
+	Identifier  *Summary `json:"identifier"`
+	Declaration *Summary `json:"declaration"`
+	Action      *Summary `json:"action"`
+	// Call is the call whose arguments contain the position.
+	Call *Summary `json:"call"`
+}
+
+// A Summary is a node without its children.
+type Summary struct {
+	Kind  string `json:"kind"`
+	Range Range  `json:"range"`
+	Text  string `json:"text"`
+}
+
+// ExpectedResult is the result of the expected method.
+type ExpectedResult struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// A Token is a token of the grammar.
+type Token struct {
+	Name  string `json:"name"`
+	Named bool   `json:"named"`
+}
+
+// HighlightResult is the result of the highlight method.
+type HighlightResult struct {
+	Highlights []Highlight `json:"highlights"`
+}
+
+// A Highlight is a capture of highlights.scm, such as keyword or string, in
+// the order a cursor returns them. Captures can nest, as a string does in an
+// interpolation; a client colors the first capture of each byte.
+type Highlight struct {
+	Capture string `json:"capture"`
+	Range   Range  `json:"range"`
+}
+
+// A Call is a call of a method in a batch.
+type Call struct {
+	Method string  `json:"method"`
+	Params Request `json:"params"`
+}
+
+// A Reply is the answer to a call in a batch: its result, or its error.
+type Reply struct {
+	Result any    `json:"result,omitempty"`
+	Error  *Error `json:"error,omitempty"`
+}
+
+// An Error is the error of a request.
+type Error struct {
+	// Code is one of the codes below, for programs to tell errors apart.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// The codes of errors, with the HTTP statuses they're sent with.
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeUnknownMethod  = "unknown_method"
+	CodeCanceled       = "canceled"
+	CodeDuplicateID    = "duplicate_id"
+	CodeTooLarge       = "too_large"
+)
+
+var statuses = map[string]int{
+	CodeInvalidRequest: http.StatusBadRequest,
+	CodeUnknownMethod:  http.StatusNotFound,
+	CodeCanceled:       http.StatusRequestTimeout,
+	CodeDuplicateID:    http.StatusConflict,
+	CodeTooLarge:       http.StatusRequestEntityTooLarge,
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func errorf(code, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// methods are the methods of version 1, which get the parsed tree of the
+// source of the request.
+var methods = map[string]func(s *Service, r *Request, tree *tree_sitter.Tree) (any, error){
+	"parse":     (*Service).parse,
+	"lint":      (*Service).lint,
+	"node":      (*Service).node,
+	"expected":  (*Service).expected,
+	"highlight": (*Service).highlight,
+}
+
+// A Service is an http.Handler that serves the API. It is safe for
+// concurrent use.
+type Service struct {
+	options Options
+	mux     *http.ServeMux
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+// New returns a service with the given options.
+func New(options Options) *Service {
+	if options.Rules == nil {
+		options.Rules = DefaultRules
+	}
+	if options.Timeout == 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.MaxRequestBytes == 0 {
+		options.MaxRequestBytes = DefaultMaxRequestBytes
+	}
+	s := &Service{options: options, mux: http.NewServeMux(), running: map[string]context.CancelFunc{}}
+	s.mux.HandleFunc("POST /v1/batch", s.serveBatch)
+	s.mux.HandleFunc("POST /v1/cancel", s.serveCancel)
+	s.mux.HandleFunc("POST /v1/{method}", s.serveMethod)
+	return s
+}
+
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Service) serveMethod(w http.ResponseWriter, r *http.Request) {
+	var request Request
+	if err := s.decode(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.Call(r.Context(), r.PathValue("method"), &request)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Service) serveBatch(w http.ResponseWriter, r *http.Request) {
+	var calls []Call
+	if err := s.decode(w, r, &calls); err != nil {
+		writeError(w, err)
+		return
+	}
+	replies := make([]Reply, len(calls))
+	for i := range calls {
+		result, err := s.Call(r.Context(), calls[i].Method, &calls[i].Params)
+		if err != nil {
+			replies[i].Error = asError(err)
+			continue
+		}
+		replies[i].Result = result
+	}
+	writeJSON(w, http.StatusOK, replies)
+}
+
+func (s *Service) serveCancel(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		ID string `json:"id"`
+	}
+	if err := s.decode(w, r, &request); err != nil {
+		writeError(w, err)
+		return
+	}
+	s.mu.Lock()
+	cancel, ok := s.running[request.ID]
+	s.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"canceled": ok})
+}
+
+// decode decodes the JSON body of r into v.
+func (s *Service) decode(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.options.MaxRequestBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			return errorf(CodeTooLarge, "the request is over %d bytes", tooLarge.Limit)
+		}
+		return errorf(CodeInvalidRequest, "%v", err)
+	}
+	return nil
+}
+
+// Call calls method with r, as a request posted to /v1/<method> does. It
+// stops when ctx is done, or when the request is canceled by its ID.
+func (s *Service) Call(ctx context.Context, method string, r *Request) (any, error) {
+	f, ok := methods[method]
+	if !ok {
+		return nil, errorf(CodeUnknownMethod, "no method %q", method)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.options.Timeout)
+	defer cancel()
+	if r.ID != "" {
+		s.mu.Lock()
+		_, running := s.running[r.ID]
+		if !running {
+			s.running[r.ID] = cancel
+		}
+		s.mu.Unlock()
+		if running {
+			return nil, errorf(CodeDuplicateID, "a request with ID %q is already running", r.ID)
+		}
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, r.ID)
+			s.mu.Unlock()
+		}()
+	}
+
+	tree, err := tree_sitter_cabin.ParseContext(ctx, []byte(r.Source))
+	if err != nil {
+		if canceled := (*tree_sitter_cabin.ParseCanceledError)(nil); errors.As(err, &canceled) {
+			return nil, errorf(CodeCanceled, "%v", err)
+		}
+		return nil, err
+	}
+	defer tree.Close()
+	return f(s, r, tree)
+}
+
+func (s *Service) parse(r *Request, tree *tree_sitter.Tree) (any, error) {
+	cursor := tree.Walk()
+	defer cursor.Close()
+	lines := newLines(r.Source)
+	return &ParseResult{Tree: lines.node(cursor), Errors: lint.SyntaxErrors(tree)}, nil
+}
+
+func (s *Service) lint(r *Request, tree *tree_sitter.Tree) (any, error) {
+	file := &lint.File{Name: r.Name, Source: []byte(r.Source), Tree: tree}
+	lines := newLines(r.Source)
+	result := &LintResult{Diagnostics: []Diagnostic{}}
+	for _, diagnostic := range lint.Run(file, s.options.Rules...) {
+		d := Diagnostic{
+			Rule:     diagnostic.Rule,
+			Severity: diagnostic.Severity.String(),
+			Message:  diagnostic.Message,
+			Range:    convertRange(diagnostic.Range),
+		}
+		if diagnostic.Fix != nil {
+			d.Fix = &Fix{Message: diagnostic.Fix.Message, Edits: []Edit{}}
+			for _, edit := range diagnostic.Fix.Edits {
+				d.Fix.Edits = append(d.Fix.Edits, Edit{Range: lines.span(edit.Start, edit.End), Text: edit.Text})
+			}
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+	return result, nil
+}
+
+func (s *Service) node(r *Request, tree *tree_sitter.Tree) (any, error) {
+	pos, err := position(r)
+	if err != nil {
+		return nil, err
+	}
+	root := tree.RootNode()
+	summary := func(node *tree_sitter.Node) *Summary {
+		if node == nil {
+			return nil
+		}
+		return &Summary{Kind: node.Kind(), Range: convertRange(node.Range()), Text: node.Utf8Text([]byte(r.Source))}
+	}
+	return &NodeResult{
+		Node:        summary(tree_sitter_cabin.NodeAt(root, pos)),
+		Identifier:  summary(tree_sitter_cabin.IdentifierAt(root, pos)),
+		Declaration: summary(tree_sitter_cabin.EnclosingDeclaration(root, pos)),
+		Action:      summary(tree_sitter_cabin.EnclosingAction(root, pos)),
+		Call:        summary(tree_sitter_cabin.EnclosingCall(root, pos)),
+	}, nil
+}
+
+func (s *Service) expected(r *Request, tree *tree_sitter.Tree) (any, error) {
+	pos, err := position(r)
+	if err != nil {
+		return nil, err
+	}
+	result := &ExpectedResult{Tokens: []Token{}}
+	for _, token := range tree_sitter_cabin.ExpectedTokens(tree, pos) {
+		result.Tokens = append(result.Tokens, Token{Name: token.Name, Named: token.Named})
+	}
+	return result, nil
+}
+
+func (s *Service) highlight(r *Request, tree *tree_sitter.Tree) (any, error) {
+	highlights, err := query.Highlights()
+	if err != nil {
+		return nil, err
+	}
+	names := highlights.CaptureNames()
+	result := &HighlightResult{Highlights: []Highlight{}}
+	for match, index := range highlights.Captures(tree.RootNode(), []byte(r.Source)) {
+		capture := match.Captures[index]
+		result.Highlights = append(result.Highlights, Highlight{Capture: names[capture.Index], Range: convertRange(capture.Node.Range())})
+	}
+	return result, nil
+}
+
+// position returns the position of r, which the method needs.
+func position(r *Request) (tree_sitter.Point, error) {
+	if r.Position == nil {
+		return tree_sitter.Point{}, errorf(CodeInvalidRequest, "the request has no position")
+	}
+	return tree_sitter.NewPoint(r.Position.Row, r.Position.Column), nil
+}
+
+func convertRange(r tree_sitter.Range) Range {
+	return Range{
+		Start:     Point{Row: r.StartPoint.Row, Column: r.StartPoint.Column},
+		End:       Point{Row: r.EndPoint.Row, Column: r.EndPoint.Column},
+		StartByte: r.StartByte,
+		EndByte:   r.EndByte,
+	}
+}
+
+// lines are the offsets of the starts of the lines of a source, to position
+// byte offsets that aren't the bounds of a node.
+type lines []uint
+
+func newLines(source string) lines {
+	l := lines{0}
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\n' {
+			l = append(l, uint(i+1))
+		}
+	}
+	return l
+}
+
+func (l lines) point(offset uint) Point {
+	row := sort.Search(len(l), func(i int) bool { return l[i] > offset }) - 1
+	return Point{Row: uint(row), Column: offset - l[row]}
+}
+
+func (l lines) span(start, end uint) Range {
+	return Range{Start: l.point(start), End: l.point(end), StartByte: start, EndByte: end}
+}
+
+// node converts the node at cursor and its descendants.
+func (l lines) node(cursor *tree_sitter.TreeCursor) *Node {
+	node := cursor.Node()
+	n := &Node{
+		Kind:    node.Kind(),
+		Named:   node.IsNamed(),
+		Field:   cursor.FieldName(),
+		Range:   convertRange(node.Range()),
+		Missing: node.IsMissing(),
+	}
+	if cursor.GotoFirstChild() {
+		for {
+			n.Children = append(n.Children, l.node(cursor))
+			if !cursor.GotoNextSibling() {
+				break
+			}
+		}
+		cursor.GotoParent()
+	}
+	return n
+}
+
+// asError converts err to the Error sent for it. Errors that aren't an
+// *Error are the service's own.
+func asError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Code: "internal", Message: err.Error()}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e := asError(err)
+	status, ok := statuses[e.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, map[string]*Error{"error": e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
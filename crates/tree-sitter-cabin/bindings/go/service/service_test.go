@@ -0,0 +1,229 @@
+package service_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/service"
+)
+
+// post posts body to path on s, and decodes the response into v.
+func post(t *testing.T, s http.Handler, path string, body any, v any) int {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	s.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data)))
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("%s: %v: %s", path, err, recorder.Body)
+	}
+	return recorder.Code
+}
+
+func TestParse(t *testing.T) {
+	s := service.New(service.Options{})
+	var result service.ParseResult
+	if code := post(t, s, "/v1/parse", service.Request{Source: "let x = 1;"}, &result); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if result.Tree.Kind != "source_file" || result.Errors != 0 {
+		t.Fatalf("parsed to %+v", result)
+	}
+	declaration := result.Tree.Children[0].Children[0]
+	if len(declaration.Children) < 2 || declaration.Children[1].Field != "name" {
+		t.Fatalf("declaration %+v has no name field", declaration)
+	}
+	if name := declaration.Children[1]; name.Range.StartByte != 4 || name.Range.End != (service.Point{Row: 0, Column: 5}) {
+		t.Errorf("name at %+v", name.Range)
+	}
+
+	if post(t, s, "/v1/parse", service.Request{Source: "let x = ;"}, &result); result.Errors == 0 {
+		t.Error("found no errors in an unfinished declaration")
+	}
+}
+
+func TestLint(t *testing.T) {
+	s := service.New(service.Options{})
+	var result service.LintResult
+	post(t, s, "/v1/lint", service.Request{Name: "main.cabin", Source: "let y = f(x\n\t-1);"}, &result)
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("got diagnostics %+v, want 1", result.Diagnostics)
+	}
+	d := result.Diagnostics[0]
+	if d.Rule != "negative-literal" || d.Severity != "warning" || d.Fix == nil || len(d.Fix.Edits) == 0 {
+		t.Fatalf("got diagnostic %+v", d)
+	}
+	// Edits are positioned from their offsets.
+	for _, edit := range d.Fix.Edits {
+		if edit.Range.Start.Row != 1 || edit.Range.Start.Column != edit.Range.StartByte-12 {
+			t.Errorf("edit %+v isn't positioned on the second line", edit)
+		}
+	}
+
+	if post(t, s, "/v1/lint", service.Request{Source: "let y = x - 1;"}, &result); len(result.Diagnostics) != 0 {
+		t.Errorf("got diagnostics %+v, want none", result.Diagnostics)
+	}
+}
+
+func TestNode(t *testing.T) {
+	s := service.New(service.Options{})
+	var result service.NodeResult
+	source := "let greet = action {\n\tsystem.terminal.print(format(name, 1));\n};\n"
+	post(t, s, "/v1/node", service.Request{Source: source, Position: &service.Point{Row: 1, Column: 31}}, &result)
+	if result.Identifier == nil || result.Identifier.Text != "name" {
+		t.Errorf("identifier %+v, want name", result.Identifier)
+	}
+	if result.Declaration == nil || !strings.HasPrefix(result.Declaration.Text, "let greet") {
+		t.Errorf("declaration %+v, want let greet", result.Declaration)
+	}
+	if result.Action == nil || !strings.HasPrefix(result.Action.Text, "action") {
+		t.Errorf("action %+v", result.Action)
+	}
+	if result.Call == nil || result.Call.Text != "format(name, 1)" {
+		t.Errorf("call %+v, want format(name, 1)", result.Call)
+	}
+
+	var failure struct{ Error service.Error }
+	if code := post(t, s, "/v1/node", service.Request{Source: source}, &failure); code != http.StatusBadRequest || failure.Error.Code != service.CodeInvalidRequest {
+		t.Errorf("request without a position: status %d, %+v", code, failure.Error)
+	}
+}
+
+func TestExpected(t *testing.T) {
+	s := service.New(service.Options{})
+	var result service.ExpectedResult
+	post(t, s, "/v1/expected", service.Request{Source: "let x = 1;", Position: &service.Point{Row: 0, Column: 5}}, &result)
+	names := map[string]bool{}
+	for _, token := range result.Tokens {
+		names[token.Name] = true
+	}
+	// After the name of a declaration, a closing parenthesis is only allowed
+	// in the parameters of an action.
+	if !names["="] || !names[":"] || names[")"] {
+		t.Errorf("expected tokens %+v, want = and : but not )", result.Tokens)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	s := service.New(service.Options{})
+	var result service.HighlightResult
+	source := "let greet = \"hi\";"
+	post(t, s, "/v1/highlight", service.Request{Source: source}, &result)
+	got := map[string]string{}
+	for _, highlight := range result.Highlights {
+		got[source[highlight.Range.StartByte:highlight.Range.EndByte]] = highlight.Capture
+	}
+	if got["let"] != "keyword" || got["\"hi\""] != "string" {
+		t.Errorf("highlights %v, want let as a keyword and \"hi\" as a string", got)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := service.New(service.Options{})
+	calls := []service.Call{
+		{Method: "parse", Params: service.Request{Source: "let x = 1;"}},
+		{Method: "format", Params: service.Request{Source: "let x = 1;"}},
+		{Method: "expected", Params: service.Request{Source: "let x = 1;"}},
+		{Method: "lint", Params: service.Request{Source: "let y = x -1;"}},
+	}
+	var replies []struct {
+		Result json.RawMessage
+		Error  *service.Error
+	}
+	if code := post(t, s, "/v1/batch", calls, &replies); code != http.StatusOK || len(replies) != len(calls) {
+		t.Fatalf("status %d, %d replies", code, len(replies))
+	}
+	for i, code := range []string{"", service.CodeUnknownMethod, service.CodeInvalidRequest, ""} {
+		switch reply := replies[i]; {
+		case code == "" && (reply.Error != nil || reply.Result == nil):
+			t.Errorf("call %d: got error %v, want a result", i, reply.Error)
+		case code != "" && (reply.Error == nil || reply.Error.Code != code):
+			t.Errorf("call %d: got error %v, want %s", i, reply.Error, code)
+		}
+	}
+	var lint service.LintResult
+	if err := json.Unmarshal(replies[3].Result, &lint); err != nil || len(lint.Diagnostics) != 1 {
+		t.Errorf("lint result %s", replies[3].Result)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	s := service.New(service.Options{MaxRequestBytes: 100})
+	var failure struct{ Error service.Error }
+	for _, test := range []struct {
+		path string
+		body any
+		code string
+	}{
+		{"/v1/format", service.Request{Source: "let x = 1;"}, service.CodeUnknownMethod},
+		{"/v1/parse", map[string]string{"text": "let x = 1;"}, service.CodeInvalidRequest},
+		{"/v1/parse", service.Request{Source: strings.Repeat("let x = 1;", 20)}, service.CodeTooLarge},
+	} {
+		failure.Error = service.Error{}
+		post(t, s, test.path, test.body, &failure)
+		if failure.Error.Code != test.code {
+			t.Errorf("%s %v: got error %+v, want %s", test.path, test.body, failure.Error, test.code)
+		}
+	}
+}
+
+// pathological is source the parser takes seconds to recover from.
+var pathological = strings.Repeat("let x = { a: f(1, [b, { c", 200000)
+
+func TestCallCanceled(t *testing.T) {
+	s := service.New(service.Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := s.Call(ctx, "parse", &service.Request{Source: "let x = 1;"})
+	if e := (*service.Error)(nil); !errors.As(err, &e) || e.Code != service.CodeCanceled {
+		t.Errorf("Call with a canceled context = %v, want a canceled error", err)
+	}
+
+	s = service.New(service.Options{Timeout: 10 * time.Millisecond})
+	start := time.Now()
+	_, err = s.Call(context.Background(), "parse", &service.Request{Source: pathological})
+	if e := (*service.Error)(nil); !errors.As(err, &e) || e.Code != service.CodeCanceled {
+		t.Errorf("Call past its timeout = %v, want a canceled error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("timed out call took %v", elapsed)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s := service.New(service.Options{})
+	done := make(chan error)
+	go func() {
+		_, err := s.Call(context.Background(), "parse", &service.Request{ID: "slow", Source: pathological})
+		done <- err
+	}()
+
+	var canceled struct{ Canceled bool }
+	for start := time.Now(); !canceled.Canceled; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("the request never started")
+		}
+		post(t, s, "/v1/cancel", map[string]string{"id": "slow"}, &canceled)
+	}
+	if err := <-done; err == nil || err.(*service.Error).Code != service.CodeCanceled {
+		t.Errorf("canceled request = %v, want a canceled error", err)
+	}
+
+	// The ID is free again once its request stops.
+	if post(t, s, "/v1/cancel", map[string]string{"id": "slow"}, &canceled); canceled.Canceled {
+		t.Error("canceled a request that had stopped")
+	}
+	var result service.ParseResult
+	if code := post(t, s, "/v1/parse", service.Request{ID: "slow", Source: "let x = 1;"}, &result); code != http.StatusOK {
+		t.Errorf("reusing the ID: status %d", code)
+	}
+}
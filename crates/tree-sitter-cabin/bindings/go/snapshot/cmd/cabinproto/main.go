@@ -0,0 +1,57 @@
+// Command cabinproto generates the protobuf schema of Cabin syntax tree
+// snapshots, cabin_ast.proto, and the Go tables the snapshot package encodes
+// with, schema_gen.go, from a node-types.json file. The kinds and fields are
+// numbered by the table numbers.txt in dir, which new ones are appended to;
+// it's started afresh if there isn't one.
+//
+// Usage:
+//
+//	cabinproto node-types.json dir
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/snapshot"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "usage: cabinproto node-types.json dir")
+		os.Exit(2)
+	}
+	if err := run(os.Args[1], os.Args[2]); err != nil {
+		fmt.Fprintln(os.Stderr, "cabinproto:", err)
+		os.Exit(1)
+	}
+}
+
+func run(nodeTypes, dir string) error {
+	data, err := os.ReadFile(nodeTypes)
+	if err != nil {
+		return err
+	}
+	numbers, err := os.ReadFile(filepath.Join(dir, "numbers.txt"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	schema, err := snapshot.ParseSchema(data, numbers)
+	if err != nil {
+		return err
+	}
+	source, err := schema.Go("snapshot")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "numbers.txt"), schema.Numbers(), 0o666); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cabin_ast.proto"), schema.Proto(), 0o666); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "schema_gen.go"), source, 0o666)
+}
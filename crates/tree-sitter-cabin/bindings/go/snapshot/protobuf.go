@@ -0,0 +1,295 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The field numbers of the messages in cabin_ast.proto.
+const (
+	treeSource = 1
+	treeRoot   = 2
+
+	nodeKind      = 1
+	nodeField     = 2
+	nodeStartByte = 3
+	nodeEndByte   = 4
+	nodeMissing   = 5
+	nodeChildren  = 6
+)
+
+// maxDepth bounds how deeply nested the nodes Unmarshal decodes can be, like
+// the recursion limit of the protobuf module.
+const maxDepth = 10000
+
+// numbers maps the kinds and fields of the schema to their numbers.
+var numbers = sync.OnceValue(func() (n struct {
+	kinds  map[Kind]uint64
+	fields map[string]uint64
+}) {
+	n.kinds = map[Kind]uint64{{Name: "ERROR", Named: true}: kindError}
+	for i, kind := range schema.Kinds {
+		if !kind.Removed {
+			n.kinds[kind] = uint64(firstKind + i)
+		}
+	}
+	n.fields = map[string]uint64{}
+	for i, field := range schema.Fields {
+		if !field.Removed {
+			n.fields[field.Name] = uint64(i + 1)
+		}
+	}
+	return n
+})
+
+// Marshal encodes tree as a cabin.ast.Tree message. It fails if a node has a
+// kind or field the grammar doesn't, such as one from a newer grammar.
+func Marshal(tree *Tree) ([]byte, error) {
+	if len(tree.Source) > math.MaxUint32 {
+		return nil, errors.New("the source is too large to encode")
+	}
+	e := &encoder{}
+	var size int
+	if tree.Root != nil {
+		var err error
+		if size, err = e.size(tree.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	var b []byte
+	if len(tree.Source) > 0 {
+		b = protowire.AppendTag(b, treeSource, protowire.BytesType)
+		b = protowire.AppendBytes(b, tree.Source)
+	}
+	if tree.Root != nil {
+		b = protowire.AppendTag(b, treeRoot, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(size))
+		b = e.append(b, tree.Root)
+	}
+	return b, nil
+}
+
+// An encoder encodes nodes. Every message is preceded by its length, so the
+// lengths of all nodes are worked out before any is written.
+type encoder struct {
+	// sizes are the lengths of the encoded nodes, in preorder.
+	sizes []int
+	next  int
+}
+
+// size returns the length of n encoded, recording it and the lengths of the
+// descendants of n.
+func (e *encoder) size(n *Node) (int, error) {
+	kind, ok := numbers().kinds[Kind{Name: n.Kind, Named: n.Named}]
+	if !ok {
+		return 0, fmt.Errorf("the grammar has no node kind %q", n.Kind)
+	}
+	field := uint64(0)
+	if n.Field != "" {
+		if field, ok = numbers().fields[n.Field]; !ok {
+			return 0, fmt.Errorf("the grammar has no field %q", n.Field)
+		}
+	}
+	if n.StartByte > n.EndByte || n.EndByte > math.MaxUint32 {
+		return 0, fmt.Errorf("%s node has an invalid range %d-%d", n.Kind, n.StartByte, n.EndByte)
+	}
+
+	i := len(e.sizes)
+	e.sizes = append(e.sizes, 0)
+	size := protowire.SizeTag(nodeKind) + protowire.SizeVarint(kind)
+	if field != 0 {
+		size += protowire.SizeTag(nodeField) + protowire.SizeVarint(field)
+	}
+	if n.StartByte != 0 {
+		size += protowire.SizeTag(nodeStartByte) + protowire.SizeVarint(uint64(n.StartByte))
+	}
+	if n.EndByte != 0 {
+		size += protowire.SizeTag(nodeEndByte) + protowire.SizeVarint(uint64(n.EndByte))
+	}
+	if n.Missing {
+		size += protowire.SizeTag(nodeMissing) + protowire.SizeVarint(1)
+	}
+	for _, child := range n.Children {
+		childSize, err := e.size(child)
+		if err != nil {
+			return 0, err
+		}
+		size += protowire.SizeTag(nodeChildren) + protowire.SizeBytes(childSize)
+	}
+	e.sizes[i] = size
+	return size, nil
+}
+
+// append appends n, whose length has been worked out, to b.
+func (e *encoder) append(b []byte, n *Node) []byte {
+	e.next++
+	b = protowire.AppendTag(b, nodeKind, protowire.VarintType)
+	b = protowire.AppendVarint(b, numbers().kinds[Kind{Name: n.Kind, Named: n.Named}])
+	if n.Field != "" {
+		b = protowire.AppendTag(b, nodeField, protowire.VarintType)
+		b = protowire.AppendVarint(b, numbers().fields[n.Field])
+	}
+	if n.StartByte != 0 {
+		b = protowire.AppendTag(b, nodeStartByte, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(n.StartByte))
+	}
+	if n.EndByte != 0 {
+		b = protowire.AppendTag(b, nodeEndByte, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(n.EndByte))
+	}
+	if n.Missing {
+		b = protowire.AppendTag(b, nodeMissing, protowire.VarintType)
+		b = protowire.AppendVarint(b, 1)
+	}
+	for _, child := range n.Children {
+		b = protowire.AppendTag(b, nodeChildren, protowire.BytesType)
+		b = protowire.AppendVarint(b, uint64(e.sizes[e.next]))
+		b = e.append(b, child)
+	}
+	return b
+}
+
+// Unmarshal decodes a cabin.ast.Tree message. Fields it doesn't know are
+// skipped, but kinds and fields the grammar doesn't have are errors, as are
+// nodes that don't lie within the source.
+func Unmarshal(data []byte) (*Tree, error) {
+	tree := &Tree{}
+	var root []byte
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		data = data[n:]
+		switch {
+		case number == treeSource && typ == protowire.BytesType:
+			source, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			tree.Source = append([]byte(nil), source...)
+			data = data[n:]
+		case number == treeRoot && typ == protowire.BytesType:
+			// The source may come after the root, and is needed to place
+			// the nodes, so the root is decoded last.
+			if root, n = protowire.ConsumeBytes(data); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		default:
+			if n = protowire.ConsumeFieldValue(number, typ, data); n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			data = data[n:]
+		}
+	}
+	if root != nil {
+		d := &decoder{source: tree.Source, lines: []uint{0}}
+		for i, c := range tree.Source {
+			if c == '\n' {
+				d.lines = append(d.lines, uint(i+1))
+			}
+		}
+		var err error
+		if tree.Root, err = d.node(root, 0); err != nil {
+			return nil, err
+		}
+	}
+	return tree, nil
+}
+
+// A decoder decodes the nodes of a tree with the given source.
+type decoder struct {
+	source []byte
+	// lines are the offsets of the starts of the lines of the source.
+	lines []uint
+}
+
+// node decodes a cabin.ast.Node message nested depth messages deep in the
+// root.
+func (d *decoder) node(data []byte, depth int) (*Node, error) {
+	if depth > maxDepth {
+		return nil, errors.New("nodes are nested too deeply")
+	}
+	n := &Node{}
+	var kind, field, start, end uint64
+	for len(data) > 0 {
+		number, typ, m := protowire.ConsumeTag(data)
+		if m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		data = data[m:]
+		if typ == protowire.VarintType && number >= nodeKind && number <= nodeMissing {
+			v, m := protowire.ConsumeVarint(data)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			data = data[m:]
+			switch number {
+			case nodeKind:
+				kind = v
+			case nodeField:
+				field = v
+			case nodeStartByte:
+				start = v
+			case nodeEndByte:
+				end = v
+			case nodeMissing:
+				n.Missing = v != 0
+			}
+			continue
+		}
+		if number == nodeChildren && typ == protowire.BytesType {
+			message, m := protowire.ConsumeBytes(data)
+			if m < 0 {
+				return nil, protowire.ParseError(m)
+			}
+			data = data[m:]
+			child, err := d.node(message, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.Children = append(n.Children, child)
+			continue
+		}
+		if m = protowire.ConsumeFieldValue(number, typ, data); m < 0 {
+			return nil, protowire.ParseError(m)
+		}
+		data = data[m:]
+	}
+
+	switch {
+	case kind == kindError:
+		n.Kind, n.Named = "ERROR", true
+	case kind >= firstKind && kind-firstKind < uint64(len(schema.Kinds)) && !schema.Kinds[kind-firstKind].Removed:
+		k := schema.Kinds[kind-firstKind]
+		n.Kind, n.Named = k.Name, k.Named
+	default:
+		return nil, fmt.Errorf("the grammar has no node kind %d", kind)
+	}
+	if field != 0 {
+		if field > uint64(len(schema.Fields)) || schema.Fields[field-1].Removed {
+			return nil, fmt.Errorf("the grammar has no field %d", field)
+		}
+		n.Field = schema.Fields[field-1].Name
+	}
+	if start > end || end > uint64(len(d.source)) {
+		return nil, fmt.Errorf("%s node has range %d-%d outside the source of %d bytes", n.Kind, start, end, len(d.source))
+	}
+	n.StartByte, n.EndByte = uint(start), uint(end)
+	n.StartPoint, n.EndPoint = d.point(n.StartByte), d.point(n.EndByte)
+	return n, nil
+}
+
+// point returns the row and column, in bytes, of offset in the source.
+func (d *decoder) point(offset uint) tree_sitter.Point {
+	row := sort.Search(len(d.lines), func(i int) bool { return d.lines[i] > offset }) - 1
+	return tree_sitter.Point{Row: uint(row), Column: offset - d.lines[row]}
+}
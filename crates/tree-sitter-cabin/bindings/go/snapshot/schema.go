@@ -0,0 +1,293 @@
+package snapshot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// A Schema numbers the node kinds and field names of the grammar for the
+// protobuf encoding. The numbers are kept in a table, numbers.txt, that new
+// kinds and fields are appended to, so that a kind keeps its number as the
+// grammar changes and old snapshots still decode.
+type Schema struct {
+	// Kinds are the node kinds, numbered from 2 in order. 0 is left
+	// unspecified and 1 is ERROR.
+	Kinds []Kind
+	// Fields are the field names, numbered from 1 in order.
+	Fields []Field
+}
+
+// A Kind is a node kind of the grammar.
+type Kind struct {
+	Name  string
+	Named bool
+	// Removed reports whether the grammar no longer has the kind, whose
+	// number is kept so that no other kind takes it.
+	Removed bool
+}
+
+// A Field is a field name of the grammar.
+type Field struct {
+	Name string
+	// Removed reports whether the grammar no longer has the field.
+	Removed bool
+}
+
+// The numbers of the kinds every schema has.
+const (
+	kindUnspecified = 0
+	kindError       = 1
+	firstKind       = 2
+)
+
+// ParseSchema numbers the node kinds and fields listed in nodeTypes, the
+// contents of a node-types.json file. Those in numbers, the contents of the
+// table written by Numbers, keep their numbers, and the rest are numbered
+// after them: kinds in the order of nodeTypes, and fields by name. A table
+// whose numbers aren't the ones it would be written with has been edited,
+// and is an error.
+func ParseSchema(nodeTypes, numbers []byte) (*Schema, error) {
+	var types []struct {
+		Type   string                     `json:"type"`
+		Named  bool                       `json:"named"`
+		Fields map[string]json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(nodeTypes, &types); err != nil {
+		return nil, err
+	}
+	s, err := parseNumbers(numbers)
+	if err != nil {
+		return nil, err
+	}
+	kinds := map[Kind]bool{}
+	var fields []string
+	for _, typ := range types {
+		kinds[Kind{Name: typ.Type, Named: typ.Named}] = true
+		for field := range typ.Fields {
+			if !slices.Contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	slices.Sort(fields)
+
+	for i, kind := range s.Kinds {
+		s.Kinds[i].Removed = !kinds[kind]
+	}
+	for _, typ := range types {
+		kind := Kind{Name: typ.Type, Named: typ.Named}
+		if !slices.Contains(s.Kinds, kind) {
+			s.Kinds = append(s.Kinds, kind)
+		}
+	}
+	for i, field := range s.Fields {
+		s.Fields[i].Removed = !slices.Contains(fields, field.Name)
+	}
+	for _, field := range fields {
+		if !slices.ContainsFunc(s.Fields, func(f Field) bool { return f.Name == field }) {
+			s.Fields = append(s.Fields, Field{Name: field})
+		}
+	}
+
+	// Enum value names share the scope of the package, so they can't repeat.
+	seen := map[string]Kind{}
+	for _, kind := range s.Kinds {
+		name := kind.enumName()
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("kinds %q and %q are both named %s", other.Name, kind.Name, name)
+		}
+		seen[name] = kind
+	}
+	return s, nil
+}
+
+// numbersHeader starts the table written by Numbers.
+const numbersHeader = `# The numbers of the Kind and Field enums of cabin_ast.proto, which
+# cabinproto appends new kinds and fields to. Snapshots are decoded by these
+# numbers, so never edit, reorder or remove a line; a kind the grammar no
+# longer has keeps its line, and its number is reserved.
+`
+
+// parseNumbers parses a table written by Numbers into a schema that has
+// nothing removed.
+func parseNumbers(numbers []byte) (*Schema, error) {
+	s := &Schema{}
+	for i, line := range strings.Split(string(numbers), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		what, rest, _ := strings.Cut(line, " ")
+		number, name, _ := strings.Cut(rest, " ")
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return nil, fmt.Errorf("numbers line %d: %q isn't a number", i+1, number)
+		}
+		var want int
+		switch what {
+		case "kind":
+			want = firstKind + len(s.Kinds)
+			kind := Kind{Name: name, Named: true}
+			if strings.HasPrefix(name, `"`) {
+				if kind.Name, err = strconv.Unquote(name); err != nil {
+					return nil, fmt.Errorf("numbers line %d: %v", i+1, err)
+				}
+				kind.Named = false
+			}
+			if slices.Contains(s.Kinds, kind) {
+				return nil, fmt.Errorf("numbers line %d: kind %s is listed twice", i+1, name)
+			}
+			s.Kinds = append(s.Kinds, kind)
+		case "field":
+			want = 1 + len(s.Fields)
+			if slices.Contains(s.Fields, Field{Name: name}) {
+				return nil, fmt.Errorf("numbers line %d: field %s is listed twice", i+1, name)
+			}
+			s.Fields = append(s.Fields, Field{Name: name})
+		default:
+			return nil, fmt.Errorf("numbers line %d: %q is neither a kind nor a field", i+1, what)
+		}
+		if n != want {
+			return nil, fmt.Errorf("numbers line %d: %s %s is numbered %d, want %d; numbers can't change", i+1, what, name, n, want)
+		}
+	}
+	return s, nil
+}
+
+// Numbers returns the table of the numbers of the schema, for ParseSchema to
+// number the next version of the grammar with.
+func (s *Schema) Numbers() []byte {
+	var b bytes.Buffer
+	b.WriteString(numbersHeader)
+	for i, kind := range s.Kinds {
+		name := kind.Name
+		if !kind.Named {
+			name = strconv.Quote(name)
+		}
+		fmt.Fprintf(&b, "kind %d %s\n", firstKind+i, name)
+	}
+	for i, field := range s.Fields {
+		fmt.Fprintf(&b, "field %d %s\n", i+1, field.Name)
+	}
+	return b.Bytes()
+}
+
+var word = regexp.MustCompile(`^\w+$`)
+
+// punctuation spells out the characters of the tokens that aren't words.
+var punctuation = map[rune]string{
+	' ': "SPACE", '!': "BANG", '"': "QUOTE", '#': "HASH", '$': "DOLLAR",
+	'%': "PERCENT", '&': "AMPERSAND", '\'': "APOSTROPHE", '(': "LPAREN",
+	')': "RPAREN", '*': "STAR", '+': "PLUS", ',': "COMMA", '-': "MINUS",
+	'.': "DOT", '/': "SLASH", ':': "COLON", ';': "SEMICOLON", '<': "LESS",
+	'=': "EQUAL", '>': "GREATER", '?': "QUESTION", '@': "AT", '[': "LBRACKET",
+	'\\': "BACKSLASH", ']': "RBRACKET", '^': "CARET", '`': "BACKTICK",
+	'{': "LBRACE", '|': "PIPE", '}': "RBRACE", '~': "TILDE",
+}
+
+// enumName returns the name of the value of the Kind enum for k.
+func (k Kind) enumName() string {
+	if k.Named {
+		return "KIND_" + strings.ToUpper(k.Name)
+	}
+	if word.MatchString(k.Name) {
+		return "KIND_TOKEN_" + strings.ToUpper(k.Name)
+	}
+	var parts []string
+	for _, r := range k.Name {
+		part, ok := punctuation[r]
+		if !ok {
+			part = fmt.Sprintf("U%04X", r)
+		}
+		parts = append(parts, part)
+	}
+	return "KIND_TOKEN_" + strings.Join(parts, "_")
+}
+
+// Proto returns the protobuf schema of snapshots of trees of the grammar.
+func (s *Schema) Proto() []byte {
+	var b bytes.Buffer
+	b.WriteString(`// Code generated by cabinproto from node-types.json and numbers.txt. DO NOT EDIT.
+
+syntax = "proto3";
+
+package cabin.ast;
+
+option go_package = "github.com/language-cabin/tree-sitter-cabin/bindings/go/snapshot";
+
+// A snapshot of a syntax tree and the source it was parsed from.
+message Tree {
+  bytes source = 1;
+  Node root = 2;
+}
+
+// A node of a tree. Its rows and columns follow from its byte offsets into
+// the source.
+message Node {
+  Kind kind = 1;
+  // The field of the parent the node is in, if any.
+  Field field = 2;
+  uint32 start_byte = 3;
+  uint32 end_byte = 4;
+  // Whether the parser inserted the node to recover from an error.
+  bool missing = 5;
+  repeated Node children = 6;
+}
+
+enum Kind {
+  KIND_UNSPECIFIED = 0;
+  KIND_ERROR = 1;
+`)
+	for i, kind := range s.Kinds {
+		switch {
+		case kind.Removed:
+			fmt.Fprintf(&b, "  reserved %d; // %s", firstKind+i, kind.enumName())
+		default:
+			fmt.Fprintf(&b, "  %s = %d;", kind.enumName(), firstKind+i)
+			if !kind.Named {
+				fmt.Fprintf(&b, " // %s", strconv.Quote(kind.Name))
+			}
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n\nenum Field {\n  FIELD_UNSPECIFIED = 0;\n")
+	for i, field := range s.Fields {
+		if field.Removed {
+			fmt.Fprintf(&b, "  reserved %d; // FIELD_%s\n", i+1, strings.ToUpper(field.Name))
+			continue
+		}
+		fmt.Fprintf(&b, "  FIELD_%s = %d;\n", strings.ToUpper(field.Name), i+1)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+// Go returns a Go file in package pkg that declares the schema as schema, for
+// the encoder to use.
+func (s *Schema) Go(pkg string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by cabinproto from node-types.json and numbers.txt. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	b.WriteString("var schema = &Schema{\nKinds: []Kind{\n")
+	for _, kind := range s.Kinds {
+		if kind.Removed {
+			fmt.Fprintf(&b, "{Name: %q, Named: %t, Removed: true},\n", kind.Name, kind.Named)
+			continue
+		}
+		fmt.Fprintf(&b, "{Name: %q, Named: %t},\n", kind.Name, kind.Named)
+	}
+	b.WriteString("},\nFields: []Field{\n")
+	for _, field := range s.Fields {
+		if field.Removed {
+			fmt.Fprintf(&b, "{Name: %q, Removed: true},\n", field.Name)
+			continue
+		}
+		fmt.Fprintf(&b, "{Name: %q},\n", field.Name)
+	}
+	b.WriteString("},\n}\n")
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,120 @@
+// Code generated by cabinproto from node-types.json and numbers.txt. DO NOT EDIT.
+
+package snapshot
+
+var schema = &Schema{
+	Kinds: []Kind{
+		{Name: "binary", Named: true},
+		{Name: "block", Named: true},
+		{Name: "comment", Named: true},
+		{Name: "declaration", Named: true},
+		{Name: "either", Named: true},
+		{Name: "either_variant", Named: true},
+		{Name: "expression", Named: true},
+		{Name: "extend", Named: true},
+		{Name: "foreach", Named: true},
+		{Name: "function", Named: true},
+		{Name: "function_call", Named: true},
+		{Name: "goto", Named: true},
+		{Name: "group", Named: true},
+		{Name: "group_field", Named: true},
+		{Name: "group_parameter", Named: true},
+		{Name: "identifier", Named: true},
+		{Name: "if_expression", Named: true},
+		{Name: "list", Named: true},
+		{Name: "literal", Named: true},
+		{Name: "match", Named: true},
+		{Name: "object_constructor", Named: true},
+		{Name: "object_value", Named: true},
+		{Name: "parameter", Named: true},
+		{Name: "postfix", Named: true},
+		{Name: "run", Named: true},
+		{Name: "source_file", Named: true},
+		{Name: "statement", Named: true},
+		{Name: "string", Named: true},
+		{Name: "tag", Named: true},
+		{Name: "type", Named: true},
+		{Name: "while_loop", Named: true},
+		{Name: " >", Named: false},
+		{Name: "!", Named: false},
+		{Name: "!=", Named: false},
+		{Name: "\"", Named: false},
+		{Name: "#", Named: false},
+		{Name: "# ", Named: false},
+		{Name: "(", Named: false},
+		{Name: ")", Named: false},
+		{Name: "*", Named: false},
+		{Name: "+", Named: false},
+		{Name: ",", Named: false},
+		{Name: "-", Named: false},
+		{Name: ".", Named: false},
+		{Name: "/", Named: false},
+		{Name: ":", Named: false},
+		{Name: "::", Named: false},
+		{Name: ";", Named: false},
+		{Name: "<", Named: false},
+		{Name: "< ", Named: false},
+		{Name: "<=", Named: false},
+		{Name: "=", Named: false},
+		{Name: "==", Named: false},
+		{Name: "=>", Named: false},
+		{Name: ">", Named: false},
+		{Name: ">=", Named: false},
+		{Name: "?", Named: false},
+		{Name: "[", Named: false},
+		{Name: "]", Named: false},
+		{Name: "^", Named: false},
+		{Name: "action", Named: false},
+		{Name: "and", Named: false},
+		{Name: "as", Named: false},
+		{Name: "editable", Named: false},
+		{Name: "either", Named: false},
+		{Name: "extend", Named: false},
+		{Name: "foreach", Named: false},
+		{Name: "group", Named: false},
+		{Name: "if", Named: false},
+		{Name: "in", Named: false},
+		{Name: "is", Named: false},
+		{Name: "let", Named: false},
+		{Name: "match", Named: false},
+		{Name: "new", Named: false},
+		{Name: "number", Named: true},
+		{Name: "or", Named: false},
+		{Name: "other_identifier", Named: true},
+		{Name: "otherwise", Named: false},
+		{Name: "pascal_case_identifier", Named: true},
+		{Name: "raw_string", Named: true},
+		{Name: "run", Named: false},
+		{Name: "visible", Named: false},
+		{Name: "while", Named: false},
+		{Name: "{", Named: false},
+		{Name: "}", Named: false},
+	},
+	Fields: []Field{
+		{Name: "arguments"},
+		{Name: "as"},
+		{Name: "binding"},
+		{Name: "body"},
+		{Name: "callee"},
+		{Name: "compile_time_arguments"},
+		{Name: "compile_time_parameters"},
+		{Name: "condition"},
+		{Name: "editable"},
+		{Name: "fields"},
+		{Name: "iterable"},
+		{Name: "label"},
+		{Name: "left"},
+		{Name: "name"},
+		{Name: "operator"},
+		{Name: "parameters"},
+		{Name: "return_type"},
+		{Name: "right"},
+		{Name: "source"},
+		{Name: "tags"},
+		{Name: "target"},
+		{Name: "type"},
+		{Name: "value"},
+		{Name: "variants"},
+		{Name: "visible"},
+	},
+}
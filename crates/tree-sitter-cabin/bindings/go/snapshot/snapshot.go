@@ -0,0 +1,79 @@
+// Package snapshot copies Cabin syntax trees into memory owned by Go, apart
+// from the tree-sitter tree they were parsed into, and encodes the copies as
+// protobuf so that they can be moved between processes. The protobuf schema,
+// cabin_ast.proto, is generated from src/node-types.json, with the numbers
+// kept in numbers.txt so that snapshots decode across grammar versions.
+package snapshot
+
+//go:generate go run ./cmd/cabinproto ../../../src/node-types.json .
+
+import (
+	"slices"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A Tree is a snapshot of a syntax tree and the source it was parsed from.
+type Tree struct {
+	Source []byte
+	Root   *Node
+}
+
+// A Node is a node of a snapshot. Unlike a tree-sitter node, it stays valid
+// after the tree it was taken from is closed.
+type Node struct {
+	// Kind is the kind of the node, or ERROR for source the parser couldn't
+	// make sense of.
+	Kind string
+	// Named reports whether the kind is a rule of the grammar, rather than a
+	// token such as a keyword or a bracket.
+	Named bool
+	// Field is the name of the field of the parent the node is in, if any.
+	Field      string
+	StartByte  uint
+	EndByte    uint
+	StartPoint tree_sitter.Point
+	EndPoint   tree_sitter.Point
+	// Missing reports whether the parser inserted the node, with no source,
+	// to recover from an error.
+	Missing  bool
+	Children []*Node
+}
+
+// New takes a snapshot of tree, which was parsed from source. The snapshot
+// holds a copy of source, so neither needs to outlive it.
+func New(tree *tree_sitter.Tree, source []byte) *Tree {
+	cursor := tree.Walk()
+	defer cursor.Close()
+	return &Tree{Source: slices.Clone(source), Root: take(cursor)}
+}
+
+// take copies the node at cursor and its descendants.
+func take(cursor *tree_sitter.TreeCursor) *Node {
+	node := cursor.Node()
+	n := &Node{
+		Kind:       node.Kind(),
+		Named:      node.IsNamed(),
+		Field:      cursor.FieldName(),
+		StartByte:  node.StartByte(),
+		EndByte:    node.EndByte(),
+		StartPoint: node.StartPosition(),
+		EndPoint:   node.EndPosition(),
+		Missing:    node.IsMissing(),
+	}
+	if cursor.GotoFirstChild() {
+		for {
+			n.Children = append(n.Children, take(cursor))
+			if !cursor.GotoNextSibling() {
+				break
+			}
+		}
+		cursor.GotoParent()
+	}
+	return n
+}
+
+// Text returns the source of the node in the source of its tree.
+func (n *Node) Text(source []byte) string {
+	return string(source[n.StartByte:n.EndByte])
+}
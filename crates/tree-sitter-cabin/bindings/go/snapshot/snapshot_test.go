@@ -0,0 +1,315 @@
+package snapshot_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/snapshot"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const source = `# A comment
+let visible Shape = either { circle: Number, empty };
+let area = action {
+	let x = "side" + 2;
+	while x > 1 { x = x - 1; };
+};
+let broken = { a: ;
+`
+
+func take(t *testing.T, source string) *snapshot.Tree {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	defer tree.Close()
+	return snapshot.New(tree, []byte(source))
+}
+
+func TestSchemaUpToDate(t *testing.T) {
+	nodeTypes, err := os.ReadFile("../../../src/node-types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	numbers, err := os.ReadFile("numbers.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ParseSchema rejects a table whose numbers were edited, and never
+	// changes the numbers it's given, so an up to date table is the checked
+	// in one with new kinds and fields appended.
+	schema, err := snapshot.ParseSchema(nodeTypes, numbers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(schema.Numbers(), numbers) {
+		t.Fatal("numbers.txt was renumbered")
+	}
+	// Swapping the names of two lines keeps the numbers in order, so the
+	// numbers of some common kinds are pinned as well.
+	for number, want := range map[int]snapshot.Kind{
+		5:  {Name: "declaration", Named: true},
+		8:  {Name: "expression", Named: true},
+		27: {Name: "source_file", Named: true},
+		49: {Name: ";", Named: false},
+		73: {Name: "let", Named: false},
+		76: {Name: "number", Named: true},
+	} {
+		if got := schema.Kinds[number-2]; got != want {
+			t.Errorf("kind %d is %+v, want %+v; numbers.txt was renumbered", number, got, want)
+		}
+	}
+	generated, err := schema.Go("snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]byte{"numbers.txt": schema.Numbers(), "cabin_ast.proto": schema.Proto(), "schema_gen.go": generated} {
+		got, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date with node-types.json; run go generate", name)
+		}
+	}
+}
+
+func TestSchemaNumbers(t *testing.T) {
+	numbers := []byte("# A comment.\nkind 2 binary\nkind 3 \"=\"\nkind 4 block\nfield 1 name\nfield 2 value\n")
+	// A kind and a field are added before the others and one of each is
+	// removed.
+	nodeTypes := []byte(`[
+		{"type": "assignment", "named": true, "fields": {"left": {}, "value": {}}},
+		{"type": "binary", "named": true},
+		{"type": "=", "named": false}
+	]`)
+	schema, err := snapshot.ParseSchema(nodeTypes, numbers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKinds := []snapshot.Kind{
+		{Name: "binary", Named: true},
+		{Name: "=", Named: false},
+		{Name: "block", Named: true, Removed: true},
+		{Name: "assignment", Named: true},
+	}
+	wantFields := []snapshot.Field{{Name: "name", Removed: true}, {Name: "value"}, {Name: "left"}}
+	if !reflect.DeepEqual(schema.Kinds, wantKinds) || !reflect.DeepEqual(schema.Fields, wantFields) {
+		t.Errorf("numbered %+v and %+v, want %+v and %+v", schema.Kinds, schema.Fields, wantKinds, wantFields)
+	}
+	proto := string(schema.Proto())
+	for _, want := range []string{"KIND_BINARY = 2;", "KIND_TOKEN_EQUAL = 3;", "reserved 4; // KIND_BLOCK", "KIND_ASSIGNMENT = 5;", "reserved 1; // FIELD_NAME", "FIELD_LEFT = 3;"} {
+		if !strings.Contains(proto, want) {
+			t.Errorf("cabin_ast.proto has no %s:\n%s", want, proto)
+		}
+	}
+
+	// Numbering again with the new table changes nothing, and a kind that
+	// comes back gets its old number.
+	again, err := snapshot.ParseSchema(nodeTypes, schema.Numbers())
+	if err != nil || !bytes.Equal(again.Numbers(), schema.Numbers()) {
+		t.Errorf("renumbered %s as %s (%v)", schema.Numbers(), again.Numbers(), err)
+	}
+	back, err := snapshot.ParseSchema([]byte(`[{"type": "block", "named": true}]`), schema.Numbers())
+	if err != nil || !reflect.DeepEqual(back.Kinds[2], snapshot.Kind{Name: "block", Named: true}) {
+		t.Errorf("block came back as %+v (%v)", back.Kinds, err)
+	}
+
+	for _, edited := range []string{
+		"kind 2 binary\nkind 4 block\n",
+		"kind 3 binary\n",
+		"kind 2 binary\nkind 3 binary\n",
+		"field 1 name\nfield 1 value\n",
+		"kind two binary\n",
+		"rule 2 binary\n",
+	} {
+		if _, err := snapshot.ParseSchema(nodeTypes, []byte(edited)); err == nil {
+			t.Errorf("numbered with the edited table %q", edited)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tree := take(t, source)
+	if tree.Root.Kind != "source_file" || len(tree.Root.Children) == 0 {
+		t.Fatalf("root is a %s with %d children", tree.Root.Kind, len(tree.Root.Children))
+	}
+	var name *snapshot.Node
+	var find func(*snapshot.Node)
+	find = func(n *snapshot.Node) {
+		for _, child := range n.Children {
+			if name == nil && child.Field == "name" {
+				name = child
+			}
+			find(child)
+		}
+	}
+	find(tree.Root)
+	if name == nil || name.Text(tree.Source) != "Shape" || name.StartPoint.Row != 1 || name.StartPoint.Column != 12 {
+		t.Errorf("name of the declaration is %+v", name)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tree := take(t, source)
+	data, err := snapshot.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := snapshot.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, tree) {
+		t.Error("the decoded snapshot differs from the encoded one")
+	}
+
+	var errors, missing int
+	var count func(*snapshot.Node)
+	count = func(n *snapshot.Node) {
+		if n.Kind == "ERROR" {
+			errors++
+		}
+		if n.Missing {
+			missing++
+		}
+		for _, child := range n.Children {
+			count(child)
+		}
+	}
+	count(decoded.Root)
+	if errors+missing == 0 {
+		t.Error("the source has no errors to encode")
+	}
+
+	encoded, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data)*5 > len(encoded) {
+		t.Errorf("encoded in %d bytes, compared to %d as JSON", len(data), len(encoded))
+	}
+}
+
+func TestMarshalUnknownKind(t *testing.T) {
+	tree := take(t, "let x = 1;")
+	tree.Root.Children[0].Kind = "unknown"
+	if _, err := snapshot.Marshal(tree); err == nil || !strings.Contains(err.Error(), `no node kind "unknown"`) {
+		t.Errorf("encoded an unknown kind: %v", err)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	node := func(fields ...uint64) []byte {
+		var b []byte
+		for i := 0; i < len(fields); i += 2 {
+			b = protowire.AppendTag(b, protowire.Number(fields[i]), protowire.VarintType)
+			b = protowire.AppendVarint(b, fields[i+1])
+		}
+		return b
+	}
+	tree := func(source string, root []byte) []byte {
+		b := protowire.AppendTag(nil, 1, protowire.BytesType)
+		b = protowire.AppendString(b, source)
+		b = protowire.AppendTag(b, 2, protowire.BytesType)
+		return protowire.AppendBytes(b, root)
+	}
+
+	for name, test := range map[string]struct {
+		data []byte
+		want string
+	}{
+		"truncated":     {tree("x", node(1, 27))[:5], "unexpected EOF"},
+		"unknown kind":  {tree("x", node(1, 1000)), "no node kind 1000"},
+		"unset kind":    {tree("x", node(3, 0)), "no node kind 0"},
+		"unknown field": {tree("x", node(1, 27, 2, 1000)), "no field 1000"},
+		"out of range":  {tree("x", node(1, 27, 4, 2)), "range 0-2 outside the source of 1 bytes"},
+		"backwards":     {tree("xy", node(1, 27, 3, 2, 4, 1)), "range 2-1"},
+	} {
+		if _, err := snapshot.Unmarshal(test.data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: got error %v, want %s", name, err, test.want)
+		}
+	}
+
+	// Fields added to the schema later are skipped.
+	root := protowire.AppendTag(node(1, 27), 100, protowire.BytesType)
+	root = protowire.AppendString(root, "new")
+	if decoded, err := snapshot.Unmarshal(tree("", root)); err != nil || decoded.Root.Kind != "source_file" {
+		t.Errorf("Unmarshal with an unknown field = %+v, %v", decoded, err)
+	}
+}
+
+// TestWireCompatible checks the encoding against the protobuf module, with
+// messages built from a descriptor of the messages in cabin_ast.proto.
+func TestWireCompatible(t *testing.T) {
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, message string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			JsonName: proto.String(name),
+			Number:   proto.Int32(number),
+			Type:     typ.Enum(),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if message != "" {
+			f.TypeName = proto.String(".cabin.ast." + message)
+		}
+		return f
+	}
+	children := field("children", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "Node")
+	children.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("cabin_ast.proto"),
+		Package: proto.String("cabin.ast"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Tree"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("source", 1, descriptorpb.FieldDescriptorProto_TYPE_BYTES, ""),
+				field("root", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "Node"),
+			}},
+			{Name: proto.String("Node"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("kind", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("field", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32, ""),
+				field("start_byte", 3, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+				field("end_byte", 4, descriptorpb.FieldDescriptorProto_TYPE_UINT32, ""),
+				field("missing", 5, descriptorpb.FieldDescriptorProto_TYPE_BOOL, ""),
+				children,
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tree := take(t, source)
+	data, err := snapshot.Marshal(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := dynamicpb.NewMessage(file.Messages().ByName("Tree"))
+	if err := proto.Unmarshal(data, message); err != nil {
+		t.Fatal(err)
+	}
+	if got := message.Get(file.Messages().ByName("Tree").Fields().ByName("source")).Bytes(); string(got) != source {
+		t.Errorf("decoded source %q", got)
+	}
+	again, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, data) {
+		t.Error("the protobuf module encodes the message differently")
+	}
+}
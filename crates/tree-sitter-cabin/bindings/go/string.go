@@ -0,0 +1,80 @@
+package tree_sitter_cabin
+
+import (
+	"errors"
+	"strings"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// A StringSegment is a part of the value of a string: either literal text, or
+// an expression interpolated with {...}.
+type StringSegment struct {
+	// Text is the literal text of the segment, or "" for an interpolation.
+	// Cabin strings have no escapes, so it's the source text as is.
+	Text string
+	// Expression is the interpolated expression, or nil for literal text.
+	Expression *tree_sitter.Node
+	// Range is the span of the text, or of the expression without its
+	// braces.
+	Range tree_sitter.Range
+}
+
+// DecodeString returns the segments of a string node, which may be wrapped in
+// expression and literal nodes, in order. Adjacent text is a single segment,
+// and the empty string has no segments. A string with syntax errors in it
+// isn't decoded.
+func DecodeString(node *tree_sitter.Node, source []byte) ([]StringSegment, error) {
+	if node = Unwrap(node); node == nil || node.Kind() != "string" {
+		return nil, errors.New("not a string node")
+	}
+	if node.HasError() {
+		return nil, errors.New("malformed string")
+	}
+
+	var segments []StringSegment
+	// The text between the tokens of the string isn't in the tree, so it's
+	// the gap between the end of one token and the start of the next.
+	text := func(start, end *tree_sitter.Node) {
+		if start.EndByte() < end.StartByte() {
+			segments = append(segments, StringSegment{
+				Text: string(source[start.EndByte():end.StartByte()]),
+				Range: tree_sitter.Range{
+					StartByte:  start.EndByte(),
+					EndByte:    end.StartByte(),
+					StartPoint: start.EndPosition(),
+					EndPoint:   end.StartPosition(),
+				},
+			})
+		}
+	}
+	previous := node.Child(0)
+	for i := uint(1); i < node.ChildCount(); i++ {
+		child := node.Child(i)
+		switch {
+		case child.IsExtra():
+		case child.Kind() == "{":
+			text(previous, child)
+		case child.Kind() == "}":
+			previous = child
+		case child.Kind() == `"`:
+			text(previous, child)
+		default:
+			segments = append(segments, StringSegment{Expression: child, Range: child.Range()})
+		}
+	}
+	return segments, nil
+}
+
+// DecodeRawString returns the value of a raw_string node, which may be
+// wrapped in expression and literal nodes: its text without the backticks.
+func DecodeRawString(node *tree_sitter.Node, source []byte) (string, error) {
+	if node = Unwrap(node); node == nil || node.Kind() != "raw_string" {
+		return "", errors.New("not a raw_string node")
+	}
+	text := strings.TrimSpace(node.Utf8Text(source))
+	if len(text) < 2 || text[0] != '`' || text[len(text)-1] != '`' {
+		return "", errors.New("malformed raw string")
+	}
+	return text[1 : len(text)-1], nil
+}
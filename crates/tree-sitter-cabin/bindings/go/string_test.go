@@ -0,0 +1,65 @@
+package tree_sitter_cabin_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+)
+
+func TestDecodeString(t *testing.T) {
+	for source, want := range map[string]string{
+		`let x = "hi {name}, you are { age + 1 }!";`: `"hi " [9-12] | {name} [13-17] | ", you are " [18-28] | {age + 1} [30-37] | "!" [39-40]`,
+		`let x = "{a} {b}";`:                         `{a} [10-11] | " " [12-13] | {b} [14-15]`,
+		`let x = "";`:                                ``,
+		"let x = \"two\nlines\";":                    `"two\nlines" [9-18]`,
+	} {
+		tree := parse(t, source)
+		value := tree.RootNode().Child(0).Child(0).ChildByFieldName("value")
+		segments, err := tree_sitter_cabin.DecodeString(value, []byte(source))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, segment := range segments {
+			span := fmt.Sprintf("[%d-%d]", segment.Range.StartByte, segment.Range.EndByte)
+			if text := source[segment.Range.StartByte:segment.Range.EndByte]; segment.Expression != nil {
+				got = append(got, fmt.Sprintf("{%s} %s", text, span))
+			} else if text == segment.Text {
+				got = append(got, fmt.Sprintf("%q %s", segment.Text, span))
+			} else {
+				t.Errorf("%s: segment %q has the span of %q", source, segment.Text, text)
+			}
+		}
+		if strings.Join(got, " | ") != want {
+			t.Errorf("DecodeString(%s) = %s, want %s", source, strings.Join(got, " | "), want)
+		}
+	}
+}
+
+func TestDecodeStringPoints(t *testing.T) {
+	source := "let x = \"a\nb{c}\";"
+	value := parse(t, source).RootNode().Child(0).Child(0).ChildByFieldName("value")
+	segments, err := tree_sitter_cabin.DecodeString(value, []byte(source))
+	if err != nil || len(segments) != 2 {
+		t.Fatalf("DecodeString = %v, %v, want 2 segments", segments, err)
+	}
+	if start, end := segments[0].Range.StartPoint, segments[0].Range.EndPoint; start.Row != 0 || start.Column != 9 || end.Row != 1 || end.Column != 1 {
+		t.Errorf("text spans %v to %v, want 0:9 to 1:1", start, end)
+	}
+	if start := segments[1].Range.StartPoint; start.Row != 1 || start.Column != 2 {
+		t.Errorf("expression starts at %v, want 1:2", start)
+	}
+}
+
+func TestDecodeRawString(t *testing.T) {
+	source := "let x = `raw {x} \"quoted\"`;"
+	value := parse(t, source).RootNode().Child(0).Child(0).ChildByFieldName("value")
+	if got, err := tree_sitter_cabin.DecodeRawString(value, []byte(source)); err != nil || got != `raw {x} "quoted"` {
+		t.Errorf("DecodeRawString = %q, %v", got, err)
+	}
+	if _, err := tree_sitter_cabin.DecodeString(value, []byte(source)); err == nil {
+		t.Error("DecodeString of a raw string succeeded")
+	}
+}
@@ -0,0 +1,189 @@
+package walk
+
+// A KindVisitor has an Enter hook for every named node kind of the Cabin
+// grammar, plus ERROR. Use Kinds to walk a tree with it, and embed
+// BaseKindVisitor to only implement the hooks you need.
+type KindVisitor interface {
+	VisitBinary(c *Cursor) Action
+	VisitBlock(c *Cursor) Action
+	VisitComment(c *Cursor) Action
+	VisitDeclaration(c *Cursor) Action
+	VisitEither(c *Cursor) Action
+	VisitEitherVariant(c *Cursor) Action
+	VisitExpression(c *Cursor) Action
+	VisitExtend(c *Cursor) Action
+	VisitForeach(c *Cursor) Action
+	VisitFunction(c *Cursor) Action
+	VisitFunctionCall(c *Cursor) Action
+	VisitGoto(c *Cursor) Action
+	VisitGroup(c *Cursor) Action
+	VisitGroupField(c *Cursor) Action
+	VisitGroupParameter(c *Cursor) Action
+	VisitIdentifier(c *Cursor) Action
+	VisitIfExpression(c *Cursor) Action
+	VisitList(c *Cursor) Action
+	VisitLiteral(c *Cursor) Action
+	VisitMatch(c *Cursor) Action
+	VisitNumber(c *Cursor) Action
+	VisitObjectConstructor(c *Cursor) Action
+	VisitObjectValue(c *Cursor) Action
+	VisitOtherIdentifier(c *Cursor) Action
+	VisitParameter(c *Cursor) Action
+	VisitPascalCaseIdentifier(c *Cursor) Action
+	VisitPostfix(c *Cursor) Action
+	VisitRawString(c *Cursor) Action
+	VisitRun(c *Cursor) Action
+	VisitSourceFile(c *Cursor) Action
+	VisitStatement(c *Cursor) Action
+	VisitString(c *Cursor) Action
+	VisitTag(c *Cursor) Action
+	VisitType(c *Cursor) Action
+	VisitWhileLoop(c *Cursor) Action
+	VisitError(c *Cursor) Action
+
+	// VisitAnonymous is called for anonymous nodes such as keywords and
+	// punctuation.
+	VisitAnonymous(c *Cursor) Action
+
+	// Leave is called when the walker leaves any node.
+	Leave(c *Cursor)
+}
+
+// BaseKindVisitor implements every hook of KindVisitor by continuing into
+// the children of the node.
+type BaseKindVisitor struct{}
+
+func (BaseKindVisitor) VisitBinary(*Cursor) Action               { return Continue }
+func (BaseKindVisitor) VisitBlock(*Cursor) Action                { return Continue }
+func (BaseKindVisitor) VisitComment(*Cursor) Action              { return Continue }
+func (BaseKindVisitor) VisitDeclaration(*Cursor) Action          { return Continue }
+func (BaseKindVisitor) VisitEither(*Cursor) Action               { return Continue }
+func (BaseKindVisitor) VisitEitherVariant(*Cursor) Action        { return Continue }
+func (BaseKindVisitor) VisitExpression(*Cursor) Action           { return Continue }
+func (BaseKindVisitor) VisitExtend(*Cursor) Action               { return Continue }
+func (BaseKindVisitor) VisitForeach(*Cursor) Action              { return Continue }
+func (BaseKindVisitor) VisitFunction(*Cursor) Action             { return Continue }
+func (BaseKindVisitor) VisitFunctionCall(*Cursor) Action         { return Continue }
+func (BaseKindVisitor) VisitGoto(*Cursor) Action                 { return Continue }
+func (BaseKindVisitor) VisitGroup(*Cursor) Action                { return Continue }
+func (BaseKindVisitor) VisitGroupField(*Cursor) Action           { return Continue }
+func (BaseKindVisitor) VisitGroupParameter(*Cursor) Action       { return Continue }
+func (BaseKindVisitor) VisitIdentifier(*Cursor) Action           { return Continue }
+func (BaseKindVisitor) VisitIfExpression(*Cursor) Action         { return Continue }
+func (BaseKindVisitor) VisitList(*Cursor) Action                 { return Continue }
+func (BaseKindVisitor) VisitLiteral(*Cursor) Action              { return Continue }
+func (BaseKindVisitor) VisitMatch(*Cursor) Action                { return Continue }
+func (BaseKindVisitor) VisitNumber(*Cursor) Action               { return Continue }
+func (BaseKindVisitor) VisitObjectConstructor(*Cursor) Action    { return Continue }
+func (BaseKindVisitor) VisitObjectValue(*Cursor) Action          { return Continue }
+func (BaseKindVisitor) VisitOtherIdentifier(*Cursor) Action      { return Continue }
+func (BaseKindVisitor) VisitParameter(*Cursor) Action            { return Continue }
+func (BaseKindVisitor) VisitPascalCaseIdentifier(*Cursor) Action { return Continue }
+func (BaseKindVisitor) VisitPostfix(*Cursor) Action              { return Continue }
+func (BaseKindVisitor) VisitRawString(*Cursor) Action            { return Continue }
+func (BaseKindVisitor) VisitRun(*Cursor) Action                  { return Continue }
+func (BaseKindVisitor) VisitSourceFile(*Cursor) Action           { return Continue }
+func (BaseKindVisitor) VisitStatement(*Cursor) Action            { return Continue }
+func (BaseKindVisitor) VisitString(*Cursor) Action               { return Continue }
+func (BaseKindVisitor) VisitTag(*Cursor) Action                  { return Continue }
+func (BaseKindVisitor) VisitType(*Cursor) Action                 { return Continue }
+func (BaseKindVisitor) VisitWhileLoop(*Cursor) Action            { return Continue }
+func (BaseKindVisitor) VisitError(*Cursor) Action                { return Continue }
+func (BaseKindVisitor) VisitAnonymous(*Cursor) Action            { return Continue }
+func (BaseKindVisitor) Leave(*Cursor)                            {}
+
+// Kinds adapts a KindVisitor to a Visitor by dispatching on the kind of each
+// node.
+func Kinds(visitor KindVisitor) Visitor {
+	return kinds{visitor}
+}
+
+type kinds struct {
+	visitor KindVisitor
+}
+
+func (k kinds) Leave(c *Cursor) {
+	k.visitor.Leave(c)
+}
+
+func (k kinds) Enter(c *Cursor) Action {
+	node := c.Node()
+	if !node.IsNamed() {
+		return k.visitor.VisitAnonymous(c)
+	}
+	switch node.Kind() {
+	case "binary":
+		return k.visitor.VisitBinary(c)
+	case "block":
+		return k.visitor.VisitBlock(c)
+	case "comment":
+		return k.visitor.VisitComment(c)
+	case "declaration":
+		return k.visitor.VisitDeclaration(c)
+	case "either":
+		return k.visitor.VisitEither(c)
+	case "either_variant":
+		return k.visitor.VisitEitherVariant(c)
+	case "expression":
+		return k.visitor.VisitExpression(c)
+	case "extend":
+		return k.visitor.VisitExtend(c)
+	case "foreach":
+		return k.visitor.VisitForeach(c)
+	case "function":
+		return k.visitor.VisitFunction(c)
+	case "function_call":
+		return k.visitor.VisitFunctionCall(c)
+	case "goto":
+		return k.visitor.VisitGoto(c)
+	case "group":
+		return k.visitor.VisitGroup(c)
+	case "group_field":
+		return k.visitor.VisitGroupField(c)
+	case "group_parameter":
+		return k.visitor.VisitGroupParameter(c)
+	case "identifier":
+		return k.visitor.VisitIdentifier(c)
+	case "if_expression":
+		return k.visitor.VisitIfExpression(c)
+	case "list":
+		return k.visitor.VisitList(c)
+	case "literal":
+		return k.visitor.VisitLiteral(c)
+	case "match":
+		return k.visitor.VisitMatch(c)
+	case "number":
+		return k.visitor.VisitNumber(c)
+	case "object_constructor":
+		return k.visitor.VisitObjectConstructor(c)
+	case "object_value":
+		return k.visitor.VisitObjectValue(c)
+	case "other_identifier":
+		return k.visitor.VisitOtherIdentifier(c)
+	case "parameter":
+		return k.visitor.VisitParameter(c)
+	case "pascal_case_identifier":
+		return k.visitor.VisitPascalCaseIdentifier(c)
+	case "postfix":
+		return k.visitor.VisitPostfix(c)
+	case "raw_string":
+		return k.visitor.VisitRawString(c)
+	case "run":
+		return k.visitor.VisitRun(c)
+	case "source_file":
+		return k.visitor.VisitSourceFile(c)
+	case "statement":
+		return k.visitor.VisitStatement(c)
+	case "string":
+		return k.visitor.VisitString(c)
+	case "tag":
+		return k.visitor.VisitTag(c)
+	case "type":
+		return k.visitor.VisitType(c)
+	case "while_loop":
+		return k.visitor.VisitWhileLoop(c)
+	case "ERROR":
+		return k.visitor.VisitError(c)
+	}
+	return Continue
+}
@@ -0,0 +1,115 @@
+// Package walk traverses Cabin syntax trees.
+//
+// Walk drives a Visitor with enter and leave events, Inspect is the
+// callback-style equivalent of go/ast.Inspect, and Kinds adapts a KindVisitor
+// with one hook per node kind. All of them move a single TreeCursor through
+// the tree instead of allocating children with Child(i).
+package walk
+
+import tree_sitter "github.com/tree-sitter/go-tree-sitter"
+
+// An Action tells the walker how to continue after entering a node.
+type Action int
+
+const (
+	// Continue descends into the children of the node.
+	Continue Action = iota
+	// SkipChildren moves on to the next sibling without visiting the
+	// children of the node. The node is still left.
+	SkipChildren
+	// Stop ends the walk immediately. No further nodes are left.
+	Stop
+)
+
+// A Visitor receives an Enter event for every node in the tree, in
+// depth-first order, and a matching Leave event once its children are done.
+type Visitor interface {
+	Enter(c *Cursor) Action
+	Leave(c *Cursor)
+}
+
+// A Cursor is the position of the walk. It is only valid during the call it
+// is passed to.
+type Cursor struct {
+	cursor    *tree_sitter.TreeCursor
+	ancestors []tree_sitter.Node
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() *tree_sitter.Node {
+	return c.cursor.Node()
+}
+
+// FieldName returns the name of the field the current node is in, or "" if
+// it isn't in one.
+func (c *Cursor) FieldName() string {
+	return c.cursor.FieldName()
+}
+
+// Parent returns the parent of the current node, or nil at the root of the
+// walk.
+func (c *Cursor) Parent() *tree_sitter.Node {
+	if len(c.ancestors) == 0 {
+		return nil
+	}
+	return &c.ancestors[len(c.ancestors)-1]
+}
+
+// Ancestors returns the ancestors of the current node, from the root of the
+// walk to the parent. The slice is reused as the walk moves on, so copy it to
+// keep it.
+func (c *Cursor) Ancestors() []tree_sitter.Node {
+	return c.ancestors
+}
+
+// Depth returns the number of ancestors of the current node.
+func (c *Cursor) Depth() int {
+	return len(c.ancestors)
+}
+
+// Walk traverses the tree rooted at root, including anonymous nodes.
+func Walk(root *tree_sitter.Node, visitor Visitor) {
+	treeCursor := root.Walk()
+	defer treeCursor.Close()
+	c := &Cursor{cursor: treeCursor}
+
+	for {
+		action := visitor.Enter(c)
+		if action == Stop {
+			return
+		}
+		if action == Continue {
+			parent := treeCursor.Node()
+			if treeCursor.GotoFirstChild() {
+				c.ancestors = append(c.ancestors, *parent)
+				continue
+			}
+		}
+		visitor.Leave(c)
+
+		for !treeCursor.GotoNextSibling() {
+			if !treeCursor.GotoParent() {
+				return
+			}
+			c.ancestors = c.ancestors[:len(c.ancestors)-1]
+			visitor.Leave(c)
+		}
+	}
+}
+
+// Inspect traverses the tree rooted at root, calling f for every node. If f
+// returns false, the children of the node are skipped.
+func Inspect(root *tree_sitter.Node, f func(*tree_sitter.Node) bool) {
+	Walk(root, inspector(f))
+}
+
+type inspector func(*tree_sitter.Node) bool
+
+func (f inspector) Enter(c *Cursor) Action {
+	if f(c.Node()) {
+		return Continue
+	}
+	return SkipChildren
+}
+
+func (f inspector) Leave(*Cursor) {}
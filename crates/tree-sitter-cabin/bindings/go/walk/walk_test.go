@@ -0,0 +1,186 @@
+package walk_test
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	tree_sitter_cabin "github.com/language-cabin/tree-sitter-cabin/bindings/go"
+	"github.com/language-cabin/tree-sitter-cabin/bindings/go/walk"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+const source = `
+let greeting = "hello";
+system.terminal.print(greeting);
+let result = match value {
+	1 => first(),
+	otherwise => second(),
+};
+`
+
+func parse(t *testing.T, source string) *tree_sitter.Tree {
+	t.Helper()
+	parser := tree_sitter.NewParser()
+	t.Cleanup(parser.Close)
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(tree_sitter_cabin.Language())); err != nil {
+		t.Fatal(err)
+	}
+	tree := parser.Parse([]byte(source), nil)
+	t.Cleanup(tree.Close)
+	return tree
+}
+
+func TestInspect(t *testing.T) {
+	tree := parse(t, source)
+
+	calls := 0
+	walk.Inspect(tree.RootNode(), func(node *tree_sitter.Node) bool {
+		if node.Kind() == "function_call" {
+			calls++
+		}
+		return true
+	})
+	if calls != 3 {
+		t.Errorf("found %d calls, want 3", calls)
+	}
+
+	calls = 0
+	walk.Inspect(tree.RootNode(), func(node *tree_sitter.Node) bool {
+		if node.Kind() == "function_call" {
+			calls++
+		}
+		return node.Kind() != "match"
+	})
+	if calls != 1 {
+		t.Errorf("found %d calls outside of match, want 1", calls)
+	}
+}
+
+type recorder struct {
+	events []string
+	depth  int
+}
+
+func (r *recorder) Enter(c *walk.Cursor) walk.Action {
+	if c.Depth() != r.depth {
+		panic("depth mismatch")
+	}
+	r.depth++
+	r.events = append(r.events, "+"+c.Node().Kind())
+	if c.Node().Kind() == "declaration" {
+		return walk.SkipChildren
+	}
+	return walk.Continue
+}
+
+func (r *recorder) Leave(c *walk.Cursor) {
+	r.depth--
+	r.events = append(r.events, "-"+c.Node().Kind())
+}
+
+func TestWalkEnterLeave(t *testing.T) {
+	tree := parse(t, "let x = 1; y;")
+	r := &recorder{}
+	walk.Walk(tree.RootNode(), r)
+
+	got := strings.Join(r.events, " ")
+	want := "+source_file +statement +declaration -declaration +; -; -statement " +
+		"+statement +expression +literal +identifier +other_identifier -other_identifier -identifier -literal -expression +; -; -statement -source_file"
+	if got != want {
+		t.Errorf("got events\n%s\nwant\n%s", got, want)
+	}
+}
+
+type calleeFinder struct {
+	walk.BaseKindVisitor
+	callees []string
+	path    []string
+	source  []byte
+}
+
+func (f *calleeFinder) VisitFunctionCall(c *walk.Cursor) walk.Action {
+	callee := c.Node().ChildByFieldName("callee")
+	f.callees = append(f.callees, callee.Utf8Text(f.source))
+	return walk.Continue
+}
+
+func (f *calleeFinder) VisitOtherIdentifier(c *walk.Cursor) walk.Action {
+	if c.Node().Utf8Text(f.source) != "print" {
+		return walk.Continue
+	}
+	for _, ancestor := range c.Ancestors() {
+		if ancestor.Kind() == "binary" {
+			f.path = append(f.path, ancestor.Kind())
+		}
+	}
+	f.path = append(f.path, c.Parent().Kind())
+	return walk.Stop
+}
+
+func TestKinds(t *testing.T) {
+	tree := parse(t, source)
+	finder := &calleeFinder{source: []byte(source)}
+	walk.Walk(tree.RootNode(), walk.Kinds(finder))
+
+	if want := []string{"system.terminal.print"}; !reflect.DeepEqual(finder.callees, want) {
+		t.Errorf("got callees %q, want %q", finder.callees, want)
+	}
+	if want := []string{"binary", "identifier"}; !reflect.DeepEqual(finder.path, want) {
+		t.Errorf("got path %q, want %q", finder.path, want)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	tree := parse(t, "let x = 1;")
+	var fields []string
+	walk.Walk(tree.RootNode(), walk.Kinds(&fieldRecorder{fields: &fields}))
+	if want := []string{"name", "value"}; !reflect.DeepEqual(fields, want) {
+		t.Errorf("got fields %q, want %q", fields, want)
+	}
+}
+
+type fieldRecorder struct {
+	walk.BaseKindVisitor
+	fields *[]string
+}
+
+func (r *fieldRecorder) VisitIdentifier(c *walk.Cursor) walk.Action {
+	*r.fields = append(*r.fields, c.FieldName())
+	return walk.Continue
+}
+
+func (r *fieldRecorder) VisitExpression(c *walk.Cursor) walk.Action {
+	*r.fields = append(*r.fields, c.FieldName())
+	return walk.SkipChildren
+}
+
+func TestKindVisitorCoversGrammar(t *testing.T) {
+	data, err := os.ReadFile("../../../src/node-types.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []struct {
+		Type  string `json:"type"`
+		Named bool   `json:"named"`
+	}
+	if err := json.Unmarshal(data, &types); err != nil {
+		t.Fatal(err)
+	}
+
+	visitor := reflect.TypeOf((*walk.KindVisitor)(nil)).Elem()
+	for _, typ := range types {
+		if !typ.Named {
+			continue
+		}
+		name := "Visit"
+		for _, part := range strings.Split(typ.Type, "_") {
+			name += strings.ToUpper(part[:1]) + part[1:]
+		}
+		if _, ok := visitor.MethodByName(name); !ok {
+			t.Errorf("KindVisitor has no %s hook for %q", name, typ.Type)
+		}
+	}
+}
@@ -1,5 +1,16 @@
 module github.com/language-cabin/tree-sitter-cabin
 
-go 1.22
+go 1.24.0
 
-require github.com/tree-sitter/go-tree-sitter v0.24.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/tree-sitter/go-tree-sitter v0.25.0
+	golang.org/x/tools v0.40.0
+	google.golang.org/protobuf v1.36.11
+)
+
+require (
+	github.com/mattn/go-pointer v0.0.1 // indirect
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+)
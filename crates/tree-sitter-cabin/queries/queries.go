@@ -0,0 +1,10 @@
+// Package queries embeds the tree-sitter query files for Cabin, so that Go
+// code can load them without knowing where the module is checked out.
+package queries
+
+import "embed"
+
+// FS holds the .scm files of this directory, such as highlights.scm.
+//
+//go:embed *.scm
+var FS embed.FS